@@ -36,7 +36,13 @@ type Args struct {
 	// Browser Validation Options (MANDATORY - CORE REQUIREMENT)
 	UseHeadlessBrowser    bool   // Enable headless browser validation
 	HeadlessTimeout       int    // Headless browser timeout in seconds
+	HeadlessConcurrency   int    // Concurrent headless validations (0 = auto)
 	ChromiumPath          string // Path to Chromium/Chrome binary
+	PuppeteerNodePath     string // Node.js binary running the Puppeteer verifier
+	PuppeteerScriptPath   string // Path to puppeteer_verifier.js
+	PlaywrightHeadless    bool   // Enable Playwright-based headless verification
+	PlaywrightScriptPath  string // Path to playwright_verifier.js
+	BrowserEngine         string // Engine for headless validation: chromium, firefox or webkit
 	DisableSandbox        bool   // Disable Chromium sandbox (use with caution)
 	ScreenshotOnExecution bool   // Take screenshots only on confirmed execution
 	ScreenshotQuality     int    // Screenshot quality (1-100, default >=90)
@@ -49,6 +55,8 @@ type Args struct {
 	Concurrence int // Number of concurrent workers
 	MaxCPU      int // Maximum CPU cores to use
 
+	MaxPayloadsPerContext int // Cap on custom-merged payloads per context (0 = no limit)
+
 	// Boolean options
 	OnlyDiscovery             bool // Only perform parameter discovery
 	Silence                   bool // Minimal output mode
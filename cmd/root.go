@@ -14,6 +14,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/hahwul/dalfox/v2/internal/browser"
 	"github.com/hahwul/dalfox/v2/internal/har"
 	"github.com/hahwul/dalfox/v2/internal/printing"
 	"github.com/hahwul/dalfox/v2/pkg/model"
@@ -66,6 +67,14 @@ blind XSS detection, and much more.`,
 		// For any custom default behavior when arguments are provided
 		// but don't match a subcommand, add that logic here
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		// Any engine but chromium runs through Playwright, so reject a typo
+		// here instead of failing every headless validation
+		if _, err := browser.ParseEngine(args.BrowserEngine); err != nil {
+			return fmt.Errorf("--browser-engine: %w", err)
+		}
+		return nil
+	},
 }
 
 // Execute runs the root command and handles any errors
@@ -122,6 +131,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&args.UseHeadlessBrowser, "headless-browser", false, "Enable REAL headless browser execution validation (CORE REQUIREMENT). Example: --headless-browser")
 	rootCmd.PersistentFlags().BoolVar(&args.ScreenshotOnExecution, "screenshot-on-execution", false, "Take screenshots ONLY after confirmed JavaScript execution (CORE REQUIREMENT). Example: --screenshot-on-execution")
 	rootCmd.PersistentFlags().IntVar(&args.HeadlessTimeout, "headless-timeout", 30, "Headless browser timeout in seconds (CORE REQUIREMENT). Example: --headless-timeout 30")
+	rootCmd.PersistentFlags().IntVar(&args.HeadlessConcurrency, "headless-concurrency", 0, "Set the number of concurrent headless browser validations (0 = half the workers, at most 10). Example: --headless-concurrency 4")
 	rootCmd.PersistentFlags().StringVar(&args.ChromiumPath, "chromium-path", "", "Path to Chromium/Chrome binary for headless validation (CORE REQUIREMENT). Example: --chromium-path /usr/bin/chromium")
 	rootCmd.PersistentFlags().BoolVar(&args.DisableSandbox, "disable-sandbox", false, "Disable Chromium sandbox (use with caution). Example: --disable-sandbox")
 	rootCmd.PersistentFlags().IntVar(&args.ScreenshotQuality, "screenshot-quality", 95, "Screenshot quality (1-100, must be >=90). Example: --screenshot-quality 95")
@@ -131,6 +141,7 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&args.Delay, "delay", 0, "Set the delay between requests to the same host in milliseconds. Example: --delay 1000")
 	rootCmd.PersistentFlags().IntVarP(&args.Concurrence, "worker", "w", 100, "Set the number of concurrent workers. Example: -w 100")
 	rootCmd.PersistentFlags().IntVar(&args.MaxCPU, "max-cpu", 1, "Set the maximum number of CPUs to use. Example: --max-cpu 1")
+	rootCmd.PersistentFlags().IntVar(&args.MaxPayloadsPerContext, "max-payloads-per-context", 0, "Test at most this many of the payloads merged with --custom-payload per context, defaults first, for a quick scan (0 = no limit). Example: --max-payloads-per-context 50")
 
 	// Bool
 	rootCmd.PersistentFlags().BoolVar(&args.OnlyDiscovery, "only-discovery", false, "Only perform parameter analysis, skip XSS scanning. Example: --only-discovery")
@@ -165,6 +176,11 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&args.Beef, "beef", false, "Enable BeEF integration metadata in output. Example: --beef")
 	rootCmd.PersistentFlags().BoolVar(&args.Vpn, "vpn", false, "Check for active VPN interfaces. Example: --vpn")
 	rootCmd.PersistentFlags().BoolVar(&args.PuppeteerHeadless, "puppeteer-headless", false, "Enable Puppeteer-based headless verification with JPG screenshots after XSS execution. Example: --puppeteer-headless")
+	rootCmd.PersistentFlags().StringVar(&args.PuppeteerNodePath, "puppeteer-node", "", "Node.js binary used for --puppeteer-headless and --playwright-headless (default: node from PATH). Example: --puppeteer-node /usr/local/bin/node")
+	rootCmd.PersistentFlags().StringVar(&args.PuppeteerScriptPath, "puppeteer-script", "", "Path to the Puppeteer verifier script (default: puppeteer_verifier.js in the working directory). Example: --puppeteer-script /opt/dalfox/puppeteer_verifier.js")
+	rootCmd.PersistentFlags().BoolVar(&args.PlaywrightHeadless, "playwright-headless", false, "Enable Playwright-based headless verification with JPG screenshots after XSS execution (needs the playwright npm package). Example: --playwright-headless")
+	rootCmd.PersistentFlags().StringVar(&args.PlaywrightScriptPath, "playwright-script", "", "Path to the Playwright verifier script (default: playwright_verifier.js in the working directory). Example: --playwright-script /opt/dalfox/playwright_verifier.js")
+	rootCmd.PersistentFlags().StringVar(&args.BrowserEngine, "browser-engine", "chromium", "Browser engine for headless validation: chromium, firefox or webkit. Firefox and WebKit run through the Playwright verifier. Example: --browser-engine firefox")
 
 	// Initialize flag groups
 	initializeFlagGroups()
@@ -258,9 +274,9 @@ func initializeFlagGroups() {
 	}
 
 	flagMap := map[string][]string{
-		"Input":    {"config", "custom-payload", "custom-blind-xss-payload", "data", "grep", "remote-payloads", "remote-wordlists", "har-file-path"},
+		"Input":    {"config", "custom-payload", "max-payloads-per-context", "custom-blind-xss-payload", "data", "grep", "remote-payloads", "remote-wordlists", "har-file-path"},
 		"Request":  {"header", "cookie", "user-agent", "method", "cookie-from-raw"},
-		"Scanning": {"param", "ignore-param", "blind", "timeout", "delay", "worker", "skip-headless", "deep-domxss", "waf-evasion", "skip-discovery", "force-headless-verification", "use-bav", "skip-bav", "skip-mining-dom", "skip-mining-dict", "skip-mining-all", "skip-xss-scanning", "only-custom-payload", "skip-grepping", "detailed-analysis", "fast-scan", "magic-char-test", "context-aware", "beef", "vpn", "puppeteer-headless"},
+		"Scanning": {"param", "ignore-param", "blind", "timeout", "delay", "worker", "skip-headless", "deep-domxss", "waf-evasion", "skip-discovery", "force-headless-verification", "use-bav", "skip-bav", "skip-mining-dom", "skip-mining-dict", "skip-mining-all", "skip-xss-scanning", "only-custom-payload", "skip-grepping", "detailed-analysis", "fast-scan", "magic-char-test", "context-aware", "beef", "vpn", "puppeteer-headless", "puppeteer-node", "puppeteer-script", "playwright-headless", "playwright-script", "browser-engine", "headless-concurrency"},
 		"Mining":   {"mining-dict-word", "mining-dict", "mining-dom"},
 		"Output":   {"output", "format", "only-poc", "report", "output-all", "output-request", "output-response", "poc-type", "report-format", "silence", "no-color", "no-spinner"},
 		"Advanced": {"custom-alert-value", "custom-alert-type", "found-action", "found-action-shell", "proxy", "ignore-return", "max-cpu", "only-discovery", "follow-redirects", "debug"},
@@ -316,6 +332,7 @@ func initConfig() {
 		UniqParam:                 args.P,
 		BlindURL:                  args.Blind,
 		CustomPayloadFile:         args.CustomPayload,
+		MaxPayloadsPerContext:     args.MaxPayloadsPerContext,
 		CustomBlindXSSPayloadFile: args.CustomBlindXSSPayloadFile,
 		CustomAlertValue:          args.CustomAlertValue,
 		CustomAlertType:           args.CustomAlertType,
@@ -356,6 +373,15 @@ func initConfig() {
 		UseHeadless:               !args.SkipHeadless,
 		UseDeepDXSS:               args.UseDeepDXSS,
 		HeadlessTimeout:           args.HeadlessTimeout,
+		HeadlessConcurrency:       args.HeadlessConcurrency,
+		ChromiumPath:              args.ChromiumPath,
+		PuppeteerNodePath:         args.PuppeteerNodePath,
+		PuppeteerScriptPath:       args.PuppeteerScriptPath,
+		PlaywrightHeadless:        args.PlaywrightHeadless,
+		PlaywrightScriptPath:      args.PlaywrightScriptPath,
+		BrowserEngine:             args.BrowserEngine,
+		DisableSandbox:            args.DisableSandbox,
+		ScreenshotQuality:         args.ScreenshotQuality,
 		OnlyPoC:                   args.OnlyPoC,
 		OutputAll:                 args.OutputAll,
 		WAF:                       false,
@@ -396,6 +422,9 @@ func initConfig() {
 		if args.CustomPayload == "" && cfgOptions.CustomPayloadFile != "" {
 			options.CustomPayloadFile = cfgOptions.CustomPayloadFile
 		}
+		if args.MaxPayloadsPerContext == 0 && cfgOptions.MaxPayloadsPerContext != 0 {
+			options.MaxPayloadsPerContext = cfgOptions.MaxPayloadsPerContext
+		}
 		if args.CustomBlindXSSPayloadFile == "" && cfgOptions.CustomBlindXSSPayloadFile != "" {
 			options.CustomBlindXSSPayloadFile = cfgOptions.CustomBlindXSSPayloadFile
 		}
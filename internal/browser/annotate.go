@@ -0,0 +1,148 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// maxAnnotations caps the boxes drawn on an annotated SVG
+const maxAnnotations = 20
+
+// annotationBox is a highlighted element in document coordinates (CSS pixels)
+type annotationBox struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	W     float64 `json:"w"`
+	H     float64 `json:"h"`
+	Label string  `json:"label"`
+}
+
+// annotationLayout is what annotationScript reports about the page
+type annotationLayout struct {
+	Width float64         `json:"width"` // document width the boxes are relative to
+	Boxes []annotationBox `json:"boxes"`
+}
+
+// annotationScript collects the boxes of elements matching the selector or, when
+// it is empty, of elements the payload landed in: elements whose text or
+// attribute values contain the payload, and event handlers whose code is part of
+// it (<svg onload=alert(1)> is parsed into onload="alert(1)").
+const annotationScript = `(function(sel, needle, max){
+	var els = [];
+	if (sel) {
+		try { els = Array.prototype.slice.call(document.querySelectorAll(sel)); } catch (e) {}
+	} else if (needle) {
+		var all = document.getElementsByTagName('*');
+		for (var i = 0; i < all.length; i++) {
+			var e = all[i], hit = false;
+			for (var j = 0; j < e.attributes.length && !hit; j++) {
+				var a = e.attributes[j];
+				hit = a.value.indexOf(needle) !== -1 ||
+					(a.name.indexOf('on') === 0 && a.value.length > 2 && needle.indexOf(a.value) !== -1);
+			}
+			for (var c = e.firstChild; c && !hit; c = c.nextSibling) {
+				hit = c.nodeType === 3 && c.nodeValue.indexOf(needle) !== -1;
+			}
+			if (hit) els.push(e);
+		}
+	}
+	var boxes = [];
+	for (var k = 0; k < els.length && boxes.length < max; k++) {
+		var r = els[k].getBoundingClientRect();
+		if (r.width === 0 && r.height === 0) continue;
+		boxes.push({x: r.left + window.scrollX, y: r.top + window.scrollY, w: r.width, h: r.height, label: els[k].tagName.toLowerCase()});
+	}
+	var d = document.documentElement;
+	return {width: Math.max(d.scrollWidth, d.clientWidth), boxes: boxes};
+})(%s, %s, %d)`
+
+// annotatedSVG takes a full page screenshot of the tab and outlines the elements
+// matching selector, or the elements containing needle when selector is empty.
+// It runs in the tab of a validation once execution was confirmed, see
+// BrowserConfig.CaptureAnnotatedSVG; there is no live tab to capture otherwise.
+func (m *Manager) annotatedSVG(ctx context.Context, selector string, needle string) ([]byte, error) {
+	var layout annotationLayout
+	if selector != "" || needle != "" {
+		sel, _ := json.Marshal(selector)
+		ndl, _ := json.Marshal(needle)
+		script := fmt.Sprintf(annotationScript, sel, ndl, maxAnnotations)
+		if err := m.runWithTimeout(ctx, chromedp.Evaluate(script, &layout)); err != nil {
+			return nil, err
+		}
+	}
+
+	format := normalizeScreenshotFormat(m.config.ScreenshotFormat)
+	quality := m.screenshotQuality()
+	captureQuality := quality
+	if format != FormatJPG {
+		captureQuality = 100
+	}
+	var raw []byte
+	if err := m.runWithTimeout(ctx, chromedp.FullScreenshot(&raw, captureQuality)); err != nil {
+		return nil, err
+	}
+	raw, err := m.limitScreenshot(raw)
+	if err != nil {
+		return nil, err
+	}
+	// the size is read from the capture, WebP and AVIF cannot be decoded
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	img, ext, err := m.encodeScreenshot(raw, format, quality)
+	if err != nil {
+		return nil, err
+	}
+	return renderAnnotatedSVG(img, ext, cfg.Width, cfg.Height, layout), nil
+}
+
+// renderAnnotatedSVG embeds img, encoded as ext and width x height pixels large,
+// in an SVG of the same size and draws the boxes of layout over it. Boxes are
+// scaled from document to image pixels, which differ when the device scale
+// factor is not 1.
+func renderAnnotatedSVG(img []byte, ext string, width, height int, layout annotationLayout) []byte {
+	scale := 1.0
+	if layout.Width > 0 {
+		scale = float64(width) / layout.Width
+	}
+	mime := "image/jpeg"
+	switch ext {
+	case FormatPNG:
+		mime = "image/png"
+	case FormatWebP:
+		mime = "image/webp"
+	case FormatAVIF:
+		mime = "image/avif"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&sb, `<image href="data:%s;base64,%s" x="0" y="0" width="%d" height="%d"/>`+"\n",
+		mime, base64.StdEncoding.EncodeToString(img), width, height)
+	for i, b := range layout.Boxes {
+		if i == maxAnnotations {
+			break
+		}
+		x, y, w, h := b.X*scale, b.Y*scale, b.W*scale, b.H*scale
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="rgba(255,0,0,0.12)" stroke="#e00" stroke-width="3"/>`+"\n", x, y, w, h)
+		var label bytes.Buffer
+		_ = xml.EscapeText(&label, []byte(fmt.Sprintf("#%d %s", i+1, b.Label)))
+		labelY := y - 6
+		if labelY < 14 {
+			labelY = y + h + 16
+		}
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" fill="#e00" font-family="monospace" font-size="14" font-weight="bold">%s</text>`+"\n", x, labelY, label.String())
+	}
+	sb.WriteString("</svg>\n")
+	return []byte(sb.String())
+}
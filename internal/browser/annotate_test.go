@@ -0,0 +1,35 @@
+package browser
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func Test_renderAnnotatedSVG(t *testing.T) {
+	img := testPNG(t, 200, 100)
+	layout := annotationLayout{
+		Width: 100, // device scale factor 2
+		Boxes: []annotationBox{
+			{X: 10, Y: 2, W: 20, H: 10, Label: "img"},
+			{X: 40, Y: 30, W: 5, H: 5, Label: `a"<b>`},
+		},
+	}
+	svg := renderAnnotatedSVG(img, FormatPNG, 200, 100, layout)
+	out := string(svg)
+
+	if err := xml.Unmarshal(svg, new(struct{})); err != nil {
+		t.Errorf("renderAnnotatedSVG() produced invalid XML: %v", err)
+	}
+	for _, want := range []string{
+		`width="200" height="100"`,
+		`href="data:image/png;base64,`,
+		`<rect x="20.0" y="4.0" width="40.0" height="20.0"`,
+		`<rect x="80.0" y="60.0" width="10.0" height="10.0"`,
+		`#2 a&#34;&lt;b&gt;`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("renderAnnotatedSVG() output lacks %q", want)
+		}
+	}
+}
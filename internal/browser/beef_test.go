@@ -0,0 +1,111 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+// fakeBeEF is a BeEF server whose hook script registers the loading browser
+type fakeBeEF struct {
+	mu     sync.Mutex
+	online []string // page URIs of hooked browsers, the index is the session
+}
+
+func (f *fakeBeEF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/hook.js":
+		f.online = append(f.online, r.Referer())
+		fmt.Fprint(w, "/* hooked */")
+	case "/api/hooks":
+		if r.URL.Query().Get("token") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"hooked-browsers":{"online":{`)
+		for i, page := range f.online {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `"%d":{"id":%d,"session":"session-%d","page_uri":%q}`, i, i+1, i, page)
+		}
+		fmt.Fprint(w, `},"offline":{}}}`)
+	default:
+		fmt.Fprintf(w, "<p>%s</p>", r.URL.Query().Get("q"))
+	}
+}
+
+func Test_fetchBeEFHooks(t *testing.T) {
+	beef := &fakeBeEF{online: []string{"http://a.example/", "http://b.example/"}}
+	srv := httptest.NewServer(beef)
+	defer srv.Close()
+
+	online, err := fetchBeEFHooks(srv.Client(), srv.URL+"/api", "secret")
+	if err != nil {
+		t.Fatalf("fetchBeEFHooks() error = %v", err)
+	}
+	if len(online) != 2 || online["session-1"].PageURI != "http://b.example/" {
+		t.Errorf("fetchBeEFHooks() = %v, want both sessions", online)
+	}
+	if _, err := fetchBeEFHooks(srv.Client(), srv.URL+"/api", "wrong"); err == nil {
+		t.Errorf("fetchBeEFHooks() with a wrong token should fail")
+	}
+}
+
+func TestManager_beefAPIURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BrowserConfig
+		hookURL string
+		want    string
+		wantErr bool
+	}{
+		{name: "derived", hookURL: "http://10.0.0.5:3000/hook.js", want: "http://10.0.0.5:3000/api"},
+		{name: "configured", config: BrowserConfig{BeEFAPIURL: "https://beef.internal/api/"}, hookURL: "http://10.0.0.5:3000/hook.js", want: "https://beef.internal/api"},
+		{name: "relative hook", hookURL: "/hook.js", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewManager(tt.config).beefAPIURL(tt.hookURL)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("beefAPIURL() = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBeEFHookResult_ApplyToPoC(t *testing.T) {
+	var poc model.PoC
+	(&BeEFHookResult{Active: true, HookID: "session-0", Count: 1}).ApplyToPoC(&poc)
+	if !poc.BeEFHookActive || poc.BeEFHookID != "session-0" || poc.BeEFHookCount != 1 {
+		t.Errorf("ApplyToPoC() = %+v", poc)
+	}
+}
+
+func TestManager_VerifyBeEFHook(t *testing.T) {
+	beef := &fakeBeEF{online: []string{"http://already.example/"}}
+	srv := httptest.NewServer(beef)
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{BeEFAPIToken: "secret"})
+	hookURL := srv.URL + "/hook.js"
+	target := srv.URL + "/search?q=" + url.QueryEscape(BeEFHookPayload(hookURL))
+	res, err := m.VerifyBeEFHook(target, hookURL)
+	if err != nil {
+		t.Fatalf("VerifyBeEFHook() error = %v", err)
+	}
+	if !res.Active || res.HookID != "session-1" || res.Count != 1 {
+		t.Errorf("VerifyBeEFHook() = %+v, want the new session only", res)
+	}
+
+	if _, err := NewManager(BrowserConfig{}).VerifyBeEFHook(target, hookURL); err == nil {
+		t.Errorf("VerifyBeEFHook() before Initialize should fail")
+	}
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// externalEncodeTimeout bounds a single run of an external image encoder
+const externalEncodeTimeout = 30 * time.Second
+
+// externalEncoder describes a command line encoder for a format the standard
+// library cannot write
+type externalEncoder struct {
+	command string
+	// args returns the arguments encoding in to out at quality 1-100
+	args func(in, out string, quality int) []string
+}
+
+// externalEncoders maps formats to the encoders tried for them, in order
+var externalEncoders = map[string][]externalEncoder{
+	FormatWebP: {{
+		command: "cwebp",
+		args: func(in, out string, quality int) []string {
+			return []string{"-quiet", "-q", strconv.Itoa(quality), in, "-o", out}
+		},
+	}},
+	FormatAVIF: {{
+		command: "avifenc",
+		args: func(in, out string, quality int) []string {
+			return []string{"-q", strconv.Itoa(quality), in, out}
+		},
+	}},
+}
+
+// lookPath finds encoder binaries; replaced in tests
+var lookPath = exec.LookPath
+
+// fallbackWarned records the formats a JPEG fallback was already logged for
+var fallbackWarned sync.Map
+
+// findEncoder returns the first installed encoder for format
+func findEncoder(format string) (externalEncoder, string, bool) {
+	for _, enc := range externalEncoders[format] {
+		if path, err := lookPath(enc.command); err == nil {
+			return enc, path, true
+		}
+	}
+	return externalEncoder{}, "", false
+}
+
+// encodeExternal encodes raw (PNG or JPEG) to format with an external encoder
+func encodeExternal(raw []byte, format string, quality int) ([]byte, error) {
+	enc, path, ok := findEncoder(format)
+	if !ok {
+		return nil, fmt.Errorf("no %s encoder installed (%s)", format, externalEncoders[format][0].command)
+	}
+
+	dir, err := os.MkdirTemp("", "dalfox-screenshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.jpg")
+	if bytes.HasPrefix(raw, pngMagic) {
+		in = filepath.Join(dir, "in.png")
+	}
+	out := filepath.Join(dir, "out."+format)
+	if err := os.WriteFile(in, raw, 0600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), externalEncodeTimeout)
+	defer cancel()
+	if msg, err := exec.CommandContext(ctx, path, enc.args(in, out, quality)...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", enc.command, err, bytes.TrimSpace(msg))
+	}
+	return os.ReadFile(out)
+}
+
+// warnFallback logs once per format that screenshots are saved as JPEG instead
+func (m *Manager) warnFallback(format string, err error) {
+	if _, warned := fallbackWarned.LoadOrStore(format, true); !warned {
+		m.logger().Warn("browser: cannot encode screenshots, saving them as JPEG", "format", format, "error", err)
+	}
+}
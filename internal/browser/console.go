@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// consoleCollector accumulates console output of a tab. Events are delivered on
+// chromedp's listener goroutine, so access is guarded by a mutex.
+type consoleCollector struct {
+	mu     sync.Mutex
+	logs   []string
+	errors []string
+}
+
+// handle records console API calls and uncaught exceptions; other events are ignored
+func (c *consoleCollector) handle(ev interface{}) {
+	switch e := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		msg := formatConsoleArgs(e.Args)
+		c.mu.Lock()
+		if e.Type == runtime.APITypeError || e.Type == runtime.APITypeAssert {
+			c.errors = append(c.errors, msg)
+		} else {
+			c.logs = append(c.logs, msg)
+		}
+		c.mu.Unlock()
+	case *runtime.EventExceptionThrown:
+		if e.ExceptionDetails == nil {
+			return
+		}
+		msg := e.ExceptionDetails.Text
+		if e.ExceptionDetails.Exception != nil && e.ExceptionDetails.Exception.Description != "" {
+			msg = e.ExceptionDetails.Exception.Description
+		}
+		c.mu.Lock()
+		c.errors = append(c.errors, msg)
+		c.mu.Unlock()
+	}
+}
+
+// snapshot returns copies of the collected logs and errors
+func (c *consoleCollector) snapshot() ([]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.logs...), append([]string(nil), c.errors...)
+}
+
+// formatConsoleArgs joins console arguments the way DevTools prints them
+func formatConsoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		switch {
+		case len(arg.Value) > 0:
+			var s string
+			if json.Unmarshal(arg.Value, &s) == nil {
+				parts = append(parts, s)
+			} else {
+				parts = append(parts, string(arg.Value))
+			}
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		default:
+			parts = append(parts, string(arg.Type))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,35 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestConsoleCollector(t *testing.T) {
+	c := &consoleCollector{}
+	c.handle(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeLog,
+		Args: []*runtime.RemoteObject{
+			{Type: runtime.TypeString, Value: []byte(`"dalfox"`)},
+			{Type: runtime.TypeNumber, Value: []byte(`1`)},
+		},
+	})
+	c.handle(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeError,
+		Args: []*runtime.RemoteObject{{Type: runtime.TypeObject, Description: "Error: boom"}},
+	})
+	c.handle(&runtime.EventExceptionThrown{
+		ExceptionDetails: &runtime.ExceptionDetails{Text: "Uncaught"},
+	})
+	c.handle("unrelated event")
+
+	logs, errs := c.snapshot()
+	if want := []string{"dalfox 1"}; !reflect.DeepEqual(logs, want) {
+		t.Errorf("logs = %v, want %v", logs, want)
+	}
+	if want := []string{"Error: boom", "Uncaught"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+}
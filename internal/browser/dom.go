@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// SentinelFunc is the global function payloads call to prove execution without a
+// dialog, e.g. <img src=x onerror=__dalfox_exec(__dalfox_canary)>.
+const SentinelFunc = "__dalfox_exec"
+
+// canaryPollInterval is how often the page is checked for sentinel calls
+const canaryPollInterval = 200 * time.Millisecond
+
+// CanaryToken returns the canary expected for a payload. It is derived from the
+// payload hash so that repeated validations of the same payload are reproducible.
+// With BrowserConfig.StrictDialogMatch only dialogs whose message contains the
+// canary (e.g. alert(__dalfox_canary)) count as execution, which keeps alerts the
+// site fires on its own from being reported.
+func CanaryToken(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return fmt.Sprintf("dalfox%x", sum[:6])
+}
+
+// installCanary registers a script that runs before any page script and defines
+// the sentinel function plus window.__dalfox_canary holding the token. With trace
+// set it also installs tracerScript. The script identifier is stored in id so the
+// caller can remove it again.
+func installCanary(token string, trace bool, id *page.ScriptIdentifier) chromedp.Action {
+	src := fmt.Sprintf(`(function(){
+	var token = %q;
+	window.__dalfox_canary = token;
+	window.__dalfox_hits = [];
+	window.%s = function(t){ window.__dalfox_hits.push(String(t)); };
+})();`, token, SentinelFunc)
+	if trace {
+		src += "\n" + tracerScript
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		sid, err := page.AddScriptToEvaluateOnNewDocument(src).Do(ctx)
+		if err == nil && id != nil {
+			*id = sid
+		}
+		return err
+	})
+}
+
+// pollCanary periodically checks whether the sentinel was invoked with token and
+// delivers the token once it was. The channel is never closed; stop polling by
+// cancelling ctx.
+func pollCanary(ctx context.Context, token string) <-chan string {
+	hitCh := make(chan string, 1)
+	expr := fmt.Sprintf(`(window.__dalfox_hits || []).indexOf(%q) !== -1`, token)
+
+	go func() {
+		ticker := time.NewTicker(canaryPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var hit bool
+				if err := chromedp.Run(ctx, chromedp.Evaluate(expr, &hit)); err == nil && hit {
+					hitCh <- token
+					return
+				}
+			}
+		}
+	}()
+	return hitCh
+}
+
+// shadowHostScript finds the first element whose attributes call the sentinel,
+// walking open shadow roots recursively, and returns the selectors of the shadow
+// hosts enclosing it joined by " >>> ", or "" for the light DOM. Closed shadow
+// roots cannot be entered.
+const shadowHostScript = `(function(needle){
+	function selector(el) {
+		var s = el.localName;
+		if (el.id) { s += '#' + CSS.escape(el.id); }
+		return s;
+	}
+	function find(root, hosts) {
+		var all = root.querySelectorAll('*');
+		for (var i = 0; i < all.length; i++) {
+			var el = all[i];
+			for (var j = 0; j < el.attributes.length; j++) {
+				if (el.attributes[j].value.indexOf(needle) !== -1) { return hosts; }
+			}
+			if (el.shadowRoot) {
+				var found = find(el.shadowRoot, hosts.concat(selector(el)));
+				if (found) { return found; }
+			}
+		}
+		return null;
+	}
+	var hosts = find(document, []);
+	return hosts ? hosts.join(' >>> ') : '';
+})(%q)`
+
+// shadowHost returns the shadow host path of the element that called the
+// sentinel, see shadowHostScript. Errors yield "" since the execution itself is
+// already confirmed.
+func shadowHost(ctx context.Context) string {
+	var hosts string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(shadowHostScript, SentinelFunc), &hosts)); err != nil {
+		return ""
+	}
+	return hosts
+}
@@ -0,0 +1,43 @@
+package browser
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestManager_ValidatePayloadShadowDOM(t *testing.T) {
+	const sink = `<img src=x onerror="__dalfox_exec(__dalfox_canary)">`
+	shadow := `<user-card id="c1"></user-card><script>
+	customElements.define('user-card', class extends HTMLElement {
+		connectedCallback() {
+			if (this.shadowRoot) { return; }
+			var inner = this.attachShadow({mode: 'open'});
+			inner.innerHTML = '<span>` + sink + `</span>';
+		}
+	});
+	var app = document.createElement('my-app');
+	document.body.appendChild(app);
+	app.attachShadow({mode: 'open'}).appendChild(document.getElementById('c1'));
+	</script>`
+
+	m := newTestManager(t, BrowserConfig{DetectDOMExecution: true})
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{name: "nested shadow roots", page: "<body>" + shadow + "</body>", want: "my-app >>> user-card#c1"},
+		{name: "light DOM", page: "<body>" + sink + "</body>", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := m.ValidatePayload("", "data:text/html,"+url.PathEscape(tt.page), "shadow-test", "html")
+			if res.Error != nil || len(res.ExecutionProofs) == 0 {
+				t.Fatalf("ValidatePayload() = %+v, want a dom-change", res)
+			}
+			if got := res.ExecutionProofs[0].ShadowHost; got != tt.want {
+				t.Errorf("ShadowHost = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Errors returned by the Manager. They are wrapped with the underlying chromedp
+// error where there is one, so callers should compare with errors.Is.
+var (
+	// ErrNotInitialized is returned when the Manager is used before Initialize
+	// or after Shutdown.
+	ErrNotInitialized = errors.New("browser not initialized")
+
+	// ErrChromeNotFound is returned when no Chrome/Chromium binary could be
+	// started. Retrying is pointless until the binary is installed.
+	ErrChromeNotFound = errors.New("chrome binary not found")
+
+	// ErrNavigationTimeout is returned when a page did not load within
+	// BrowserConfig.Timeout. The target may just be slow, so a retry can help.
+	ErrNavigationTimeout = errors.New("navigation timed out")
+
+	// ErrPayloadTimeout is returned when a validation did not finish within
+	// BrowserConfig.TotalPayloadTimeout.
+	ErrPayloadTimeout = errors.New("validation exceeded the total payload timeout")
+
+	// ErrInvalidContext is returned when the context passed to a validation is
+	// not one ParseContext accepts.
+	ErrInvalidContext = errors.New("invalid execution context")
+
+	// ErrInvalidEngine is returned for a browser engine ParseEngine does not
+	// accept.
+	ErrInvalidEngine = errors.New("invalid browser engine")
+
+	// ErrValidationPanic is returned when a validation panicked. The browser is
+	// restarted, so concurrent validations may fail too.
+	ErrValidationPanic = errors.New("validation panicked")
+)
+
+// launchError classifies an error from starting the browser
+func launchError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrChromeNotFound, err)
+	}
+	return err
+}
+
+// permanentNavErrors are Chrome net errors that a retry cannot fix
+var permanentNavErrors = []string{
+	"net::ERR_NAME_NOT_RESOLVED",
+	"net::ERR_INVALID_URL",
+	"net::ERR_UNKNOWN_URL_SCHEME",
+	"net::ERR_DISALLOWED_URL_SCHEME",
+	"net::ERR_ADDRESS_INVALID",
+	"net::ERR_BLOCKED_BY_CLIENT",
+}
+
+// isPermanentNavError reports whether a navigation error should not be retried
+func isPermanentNavError(err error) bool {
+	msg := err.Error()
+	for _, code := range permanentNavErrors {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// navigationError classifies an error from loading a page
+func navigationError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrNavigationTimeout, err)
+	}
+	return err
+}
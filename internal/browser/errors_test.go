@@ -0,0 +1,62 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func Test_launchError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not in PATH", err: &exec.Error{Name: "google-chrome", Err: exec.ErrNotFound}, want: true},
+		{name: "missing exec path", err: &os.PathError{Op: "fork/exec", Path: "/nope/chrome", Err: os.ErrNotExist}, want: true},
+		{name: "other", err: errors.New("websocket url timeout reached"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := launchError(tt.err)
+			if errors.Is(got, ErrChromeNotFound) != tt.want {
+				t.Errorf("launchError() = %v, is ErrChromeNotFound want %v", got, tt.want)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("launchError() lost the underlying error")
+			}
+		})
+	}
+}
+
+func Test_navigationError(t *testing.T) {
+	err := navigationError(fmt.Errorf("page load: %w", context.DeadlineExceeded))
+	if !errors.Is(err, ErrNavigationTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("navigationError() = %v, want ErrNavigationTimeout wrapping the deadline", err)
+	}
+	other := errors.New("net::ERR_NAME_NOT_RESOLVED")
+	if err := navigationError(other); errors.Is(err, ErrNavigationTimeout) || err != other {
+		t.Errorf("navigationError() = %v, want the error unchanged", err)
+	}
+}
+
+func Test_isPermanentNavError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("page load error net::ERR_NAME_NOT_RESOLVED"), true},
+		{errors.New("page load error net::ERR_INVALID_URL"), true},
+		{errors.New("page load error net::ERR_CONNECTION_RESET"), false},
+		{errors.New("page load error net::ERR_EMPTY_RESPONSE"), false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := isPermanentNavError(tt.err); got != tt.want {
+			t.Errorf("isPermanentNavError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
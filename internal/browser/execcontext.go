@@ -0,0 +1,51 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is where a payload was injected, reported as
+// ExecutionProof.ExecutionContext. The values match the ones documented for
+// model.PoC.ExecutionContext.
+type Context string
+
+// Execution contexts accepted by ValidatePayload and its variants
+const (
+	ContextHTML     Context = "html"
+	ContextAttr     Context = "attribute"
+	ContextJS       Context = "javascript"
+	ContextStored   Context = "stored"   // set by VerifyStoredXSS
+	ContextHeadless Context = "headless" // plain page checks without an injection point
+)
+
+// contextAliases maps the spellings found across dalfox, such as the scanner's
+// inHTML/inATTR/inJS inject types, to their Context
+var contextAliases = map[string]Context{
+	"html":       ContextHTML,
+	"inhtml":     ContextHTML,
+	"attr":       ContextAttr,
+	"attribute":  ContextAttr,
+	"inattr":     ContextAttr,
+	"js":         ContextJS,
+	"javascript": ContextJS,
+	"script":     ContextJS,
+	"injs":       ContextJS,
+	"stored":     ContextStored,
+	"headless":   ContextHeadless,
+}
+
+// ParseContext returns the Context named by s, case-insensitively and with
+// aliases such as "attr" or "inJS". An empty s is the empty Context, for
+// validations that do not describe an injection point. Other values fail with
+// ErrInvalidContext.
+func ParseContext(s string) (Context, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if c, ok := contextAliases[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidContext, s)
+}
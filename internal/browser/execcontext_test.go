@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseContext(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Context
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "html", want: ContextHTML},
+		{in: "inHTML", want: ContextHTML},
+		{in: " Attr ", want: ContextAttr},
+		{in: "inATTR", want: ContextAttr},
+		{in: "javascript", want: ContextJS},
+		{in: "inJS", want: ContextJS},
+		{in: "stored", want: ContextStored},
+		{in: "HEADLESS", want: ContextHeadless},
+		{in: "css", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseContext(tt.in)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseContext(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidContext) {
+				t.Errorf("ParseContext(%q) error = %v, want %v", tt.in, err, ErrInvalidContext)
+			}
+		})
+	}
+}
+
+func TestManager_ValidatePayloadContext(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{})
+	page := "data:text/html,<script>alert(1)</script>"
+	res := m.ValidatePayload("", page, "<script>alert(1)</script>", "inATTR")
+	if !res.ExecutionDetected || res.ExecutionProofs[0].ExecutionContext != string(ContextAttr) {
+		t.Errorf("ValidatePayload() = %+v, want execution in %q", res, ContextAttr)
+	}
+	if res := m.ValidatePayload("", page, "<script>alert(1)</script>", "css"); !errors.Is(res.Error, ErrInvalidContext) {
+		t.Errorf("ValidatePayload() with an unknown context error = %v, want %v", res.Error, ErrInvalidContext)
+	}
+}
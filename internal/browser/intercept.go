@@ -0,0 +1,163 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// needsInterception reports whether requests of a validation tab have to be
+// paused through the Fetch domain.
+func (m *Manager) needsInterception() bool {
+	return m.config.ProxyUsername != "" || m.config.BasicAuthUser != "" ||
+		len(m.config.BlockResourceTypes) > 0 || len(m.config.BlockedDomains) > 0
+}
+
+// blocksRequest reports whether a paused request is aborted instead of loaded,
+// either for its resource type or for its host
+func (m *Manager) blocksRequest(e *fetch.EventRequestPaused) bool {
+	if m.blocksResource(e.ResourceType) {
+		return true
+	}
+	return e.Request != nil && m.blocksHost(e.Request.URL)
+}
+
+// blocksResource reports whether requests of type t are aborted instead of
+// loaded. Documents are never blocked since the validated page is one.
+func (m *Manager) blocksResource(t network.ResourceType) bool {
+	if t == network.ResourceTypeDocument {
+		return false
+	}
+	for _, blocked := range m.config.BlockResourceTypes {
+		if strings.EqualFold(blocked, t.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocksHost reports whether the host of url is one of BlockedDomains or a
+// subdomain of one. Unlike resource types this applies to documents too, so
+// out-of-scope frames and redirects are not loaded either.
+func (m *Manager) blocksHost(url string) bool {
+	if len(m.config.BlockedDomains) == 0 {
+		return false
+	}
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range m.config.BlockedDomains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(d), "*"), "."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// interceptTasks enables the Fetch domain when interception is required by the
+// configuration or forced by the caller. Every paused request must then be
+// answered by handleFetchEvent.
+func (m *Manager) interceptTasks(force bool) chromedp.Tasks {
+	if !force && !m.needsInterception() {
+		return nil
+	}
+	return chromedp.Tasks{fetch.Enable().WithHandleAuthRequests(true)}
+}
+
+// postRequest turns the next document navigation of a tab into a POST
+type postRequest struct {
+	Body        string
+	ContentType string
+}
+
+// contentType returns the Content-Type of the POST body
+func (p *postRequest) contentType() string {
+	if p.ContentType == "" {
+		return "application/x-www-form-urlencoded"
+	}
+	return p.ContentType
+}
+
+// continueParams returns how a paused request is resumed. The first document
+// request of a validation with a pending POST override is rewritten to carry
+// the body; everything else continues unchanged.
+func continueParams(e *fetch.EventRequestPaused, state *tabState) *fetch.ContinueRequestParams {
+	params := fetch.ContinueRequest(e.RequestID)
+	if state == nil || e.ResourceType != network.ResourceTypeDocument {
+		return params
+	}
+	post := state.takePOST()
+	if post == nil {
+		return params
+	}
+
+	headers := make([]*fetch.HeaderEntry, 0, len(e.Request.Headers)+1)
+	for k, v := range e.Request.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			continue
+		}
+		headers = append(headers, &fetch.HeaderEntry{Name: k, Value: fmt.Sprint(v)})
+	}
+	headers = append(headers, &fetch.HeaderEntry{Name: "Content-Type", Value: post.contentType()})
+
+	return params.
+		WithMethod("POST").
+		WithPostData(base64.StdEncoding.EncodeToString([]byte(post.Body))).
+		WithHeaders(headers)
+}
+
+// handleFetchEvent answers paused requests, aborting the blocked ones, and
+// authentication challenges. It is
+// called from a target listener and must not block, so commands are issued from
+// a separate goroutine.
+func (m *Manager) handleFetchEvent(ctx context.Context, ev interface{}, state *tabState) {
+	switch e := ev.(type) {
+	case *fetch.EventRequestPaused:
+		if m.blocksRequest(e) {
+			go func() {
+				_ = chromedp.Run(ctx, fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient))
+			}()
+			return
+		}
+		params := continueParams(e, state)
+		go func() {
+			_ = chromedp.Run(ctx, params)
+		}()
+	case *fetch.EventAuthRequired:
+		resp := m.authResponse(e, state)
+		go func() {
+			_ = chromedp.Run(ctx, fetch.ContinueWithAuth(e.RequestID, resp))
+		}()
+	}
+}
+
+// authResponse answers an authentication challenge with the proxy credentials
+// or, for the site itself, with BasicAuthUser/BasicAuthPass. A second challenge
+// for the same request means the credentials were rejected and is cancelled,
+// as answering it again would loop; the page then shows the 401 response.
+func (m *Manager) authResponse(e *fetch.EventAuthRequired, state *tabState) *fetch.AuthChallengeResponse {
+	user, pass := m.config.BasicAuthUser, m.config.BasicAuthPass
+	if e.AuthChallenge != nil && e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		user, pass = m.config.ProxyUsername, m.config.ProxyPassword
+	}
+	if user == "" {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	}
+	if state != nil && !state.firstAuthAttempt(e.RequestID) {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: user,
+		Password: pass,
+	}
+}
@@ -0,0 +1,264 @@
+package browser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+func Test_continueParams(t *testing.T) {
+	doc := &fetch.EventRequestPaused{
+		RequestID:    "1",
+		ResourceType: network.ResourceTypeDocument,
+		Request:      &network.Request{URL: "http://example.com/search", Method: "GET", Headers: network.Headers{"Accept": "text/html"}},
+	}
+	img := &fetch.EventRequestPaused{
+		RequestID:    "2",
+		ResourceType: network.ResourceTypeImage,
+		Request:      &network.Request{URL: "http://example.com/a.png", Method: "GET"},
+	}
+
+	state := newTabState()
+	state.post = &postRequest{Body: "q=<svg>"}
+
+	if got := continueParams(img, state); got.Method != "" {
+		t.Errorf("non-document request was rewritten to %q", got.Method)
+	}
+	got := continueParams(doc, state)
+	if got.Method != "POST" {
+		t.Fatalf("document request method = %q, want POST", got.Method)
+	}
+	if body, _ := base64.StdEncoding.DecodeString(got.PostData); string(body) != "q=<svg>" {
+		t.Errorf("post data = %q, want %q", body, "q=<svg>")
+	}
+	var contentType string
+	for _, h := range got.Headers {
+		if h.Name == "Content-Type" {
+			contentType = h.Value
+		}
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q, want form default", contentType)
+	}
+
+	// the override only applies once
+	if again := continueParams(doc, state); again.Method != "" {
+		t.Errorf("POST override applied twice")
+	}
+}
+
+func TestManager_ValidatePayloadPOST(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			fmt.Fprint(w, "<p>get</p>")
+			return
+		}
+		r.ParseForm()
+		fmt.Fprintf(w, "<p>%s</p>", r.PostForm.Get("q"))
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{})
+	res := m.ValidatePayloadPOST("", srv.URL, "q=<img src=x onerror=alert(1)>", "", "<img src=x onerror=alert(1)>", "html")
+	if res.Error != nil {
+		t.Fatalf("ValidatePayloadPOST() error = %v", res.Error)
+	}
+	if !res.ExecutionDetected {
+		t.Fatalf("ValidatePayloadPOST() did not detect execution")
+	}
+	var poc model.PoC
+	res.ExecutionProofs[0].ApplyToPoC(&poc)
+	if !strings.HasPrefix(poc.RawHTTPRequest, "POST / HTTP/1.1\r\n") || !strings.HasSuffix(poc.RawHTTPRequest, "\r\n\r\nq=<img src=x onerror=alert(1)>") {
+		t.Errorf("RawHTTPRequest = %q, want the rewritten POST", poc.RawHTTPRequest)
+	}
+	if !strings.HasPrefix(poc.RawHTTPResponse, "HTTP/1.1 200 OK\r\n") || !strings.Contains(poc.RawHTTPResponse, "<p><img src=x onerror=alert(1)></p>") {
+		t.Errorf("RawHTTPResponse = %q, want status, headers and body", poc.RawHTTPResponse)
+	}
+}
+
+func TestManager_blocksResource(t *testing.T) {
+	m := NewManager(BrowserConfig{BlockResourceTypes: []string{"image", "Stylesheet", "Document"}})
+	tests := []struct {
+		t    network.ResourceType
+		want bool
+	}{
+		{network.ResourceTypeImage, true},
+		{network.ResourceTypeStylesheet, true},
+		{network.ResourceTypeFont, false},
+		{network.ResourceTypeScript, false},
+		{network.ResourceTypeDocument, false},
+	}
+	for _, tt := range tests {
+		if got := m.blocksResource(tt.t); got != tt.want {
+			t.Errorf("blocksResource(%s) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+	if !m.needsInterception() {
+		t.Errorf("needsInterception() = false with blocked resource types")
+	}
+}
+
+func TestManager_ValidatePayloadBlockResourceTypes(t *testing.T) {
+	var imageRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a.png" {
+			imageRequests.Add(1)
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<img src="/a.png"><script>alert(1)</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{BlockResourceTypes: []string{"Image"}})
+	res := m.ValidatePayload("", srv.URL, "block-test", "html")
+	if res.Error != nil || !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	if n := imageRequests.Load(); n != 0 {
+		t.Errorf("blocked image was requested %d times", n)
+	}
+}
+
+func TestManager_blocksHost(t *testing.T) {
+	m := NewManager(BrowserConfig{BlockedDomains: []string{"Analytics.example", "*.ads.test", " cdn.test "}})
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://analytics.example/collect", true},
+		{"https://www.ANALYTICS.example:8443/collect", true},
+		{"https://ads.test/banner.js", true},
+		{"https://eu.ads.test/banner.js", true},
+		{"https://cdn.test/lib.js", true},
+		{"https://notanalytics.example/", false},
+		{"https://example.com/?next=analytics.example", false},
+		{"data:text/html,x", false},
+	}
+	for _, tt := range tests {
+		if got := m.blocksHost(tt.url); got != tt.want {
+			t.Errorf("blocksHost(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+	if NewManager(BrowserConfig{}).blocksHost("https://analytics.example/") {
+		t.Errorf("blocksHost() without BlockedDomains = true")
+	}
+}
+
+func TestManager_ValidatePayloadBlockedDomains(t *testing.T) {
+	var trackerRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/track.js" {
+			trackerRequests.Add(1)
+			return
+		}
+		// the tracker is the same server under another host name
+		port := r.Host[strings.LastIndex(r.Host, ":"):]
+		fmt.Fprintf(w, `<script src="http://localhost%s/track.js"></script><script>alert(1)</script>`, port)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{BlockedDomains: []string{"localhost"}})
+	res := m.ValidatePayload("", srv.URL, "blocked-domain-test", "html")
+	if res.Error != nil || !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	if n := trackerRequests.Load(); n != 0 {
+		t.Errorf("blocked domain was requested %d times", n)
+	}
+}
+
+func TestManager_authResponse(t *testing.T) {
+	m := NewManager(BrowserConfig{BasicAuthUser: "site", BasicAuthPass: "site-pass", ProxyUsername: "proxy", ProxyPassword: "proxy-pass"})
+	server := &fetch.EventAuthRequired{RequestID: "1", AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer}}
+	proxy := &fetch.EventAuthRequired{RequestID: "2", AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceProxy}}
+	state := newTabState()
+
+	if got := m.authResponse(server, state); got.Response != fetch.AuthChallengeResponseResponseProvideCredentials || got.Username != "site" || got.Password != "site-pass" {
+		t.Errorf("server challenge answered with %+v", got)
+	}
+	if got := m.authResponse(proxy, state); got.Username != "proxy" || got.Password != "proxy-pass" {
+		t.Errorf("proxy challenge answered with %+v", got)
+	}
+	if got := m.authResponse(server, state); got.Response != fetch.AuthChallengeResponseResponseCancelAuth {
+		t.Errorf("repeated challenge answered with %+v, want CancelAuth", got)
+	}
+	if got := NewManager(BrowserConfig{}).authResponse(server, state); got.Response != fetch.AuthChallengeResponseResponseDefault {
+		t.Errorf("challenge without credentials answered with %+v, want Default", got)
+	}
+}
+
+func TestManager_ValidatePayloadBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dalfox"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "<script>alert('authenticated')</script>")
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{BasicAuthUser: "admin", BasicAuthPass: "s3cret"})
+	res := m.ValidatePayload("", srv.URL, "auth-test", "html")
+	if res.Error != nil || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "authenticated" {
+		t.Errorf("Evidence = %q, want %q", got, "authenticated")
+	}
+
+	m = newTestManager(t, BrowserConfig{BasicAuthUser: "admin", BasicAuthPass: "wrong"})
+	if res := m.ValidatePayload("", srv.URL, "auth-test", "html"); res.ExecutionDetected {
+		t.Errorf("ValidatePayload() with rejected credentials detected execution")
+	}
+}
+
+func TestManager_ValidatePayloadProxy(t *testing.T) {
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxy:proxy-pass"))
+	var proxied, bypassed atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Hostname() == "bypass.test" {
+			bypassed.Add(1)
+		}
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="dalfox"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		proxied.Add(1)
+		fmt.Fprint(w, `<script>alert(1)</script>`)
+	}))
+	defer proxy.Close()
+
+	m := newTestManager(t, BrowserConfig{
+		ProxyServer:     proxy.URL,
+		ProxyBypassList: "bypass.test",
+		ProxyUsername:   "proxy",
+		ProxyPassword:   "proxy-pass",
+	})
+
+	// xss.test does not resolve, so only the proxy can serve it
+	res := m.ValidatePayload("", "http://xss.test/", "proxy-test", "html")
+	if !res.ExecutionDetected {
+		t.Errorf("ValidatePayload() through the proxy = %+v, want execution", res)
+	}
+	if proxied.Load() == 0 {
+		t.Errorf("no authenticated request reached the proxy")
+	}
+
+	if res := m.ValidatePayload("", "http://bypass.test/", "bypass-test", "html"); res.ExecutionDetected {
+		t.Errorf("ValidatePayload() of a bypassed host detected execution")
+	}
+	if n := bypassed.Load(); n != 0 {
+		t.Errorf("bypassed host was requested through the proxy %d times", n)
+	}
+}
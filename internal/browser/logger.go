@@ -0,0 +1,28 @@
+package browser
+
+import "log/slog"
+
+// Logger receives the diagnostics of a Manager, such as screenshot encoder
+// fallbacks. Each method takes a message followed by alternating keys and
+// values, so a *slog.Logger can be used as is.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// SetLogger routes the diagnostics of the manager to l. A nil l restores the
+// default, slog.Default(), which writes through the standard log package; pass
+// slog.New(slog.DiscardHandler) to silence them. Call it before validating.
+func (m *Manager) SetLogger(l Logger) {
+	m.log = l
+}
+
+// logger returns the Logger set with SetLogger or the default
+func (m *Manager) logger() Logger {
+	if m.log == nil {
+		return slog.Default()
+	}
+	return m.log
+}
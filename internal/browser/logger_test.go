@@ -0,0 +1,31 @@
+package browser
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestManager_SetLogger(t *testing.T) {
+	m := NewManager(BrowserConfig{})
+	if m.logger() != slog.Default() {
+		t.Errorf("logger() without SetLogger is not slog.Default()")
+	}
+
+	var logs bytes.Buffer
+	m.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	fallbackWarned.Delete("test-format")
+	defer fallbackWarned.Delete("test-format")
+	m.warnFallback("test-format", errors.New("cwebp: not found"))
+	m.warnFallback("test-format", errors.New("cwebp: not found"))
+	if got := logs.String(); strings.Count(got, "level=WARN") != 1 || !strings.Contains(got, "format=test-format") {
+		t.Errorf("fallback warning logged as %q, want one WARN record", got)
+	}
+
+	m.SetLogger(nil)
+	if m.logger() != slog.Default() {
+		t.Errorf("SetLogger(nil) did not restore the default")
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
-	"encoding/base64"
+	"errors"
 	"fmt"
-	"image"
-	"image/jpeg"
-	"io/ioutil"
-	"os"
+	neturl "net/url"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 )
@@ -21,22 +24,92 @@ import (
 // Manager handles headless browser sessions using Chrome DevTools Protocol.
 // This provides a reliable headless Chromium validation path. The implementation
 // strictly follows the snapshot rules: screenshots are taken only when execution
-// is confirmed (alert/confirm/prompt or verified stored execution). PNG is
-// converted to JPEG and stored under snapshots/jpg/ with quality >=90.
+// is confirmed (alert/confirm/prompt or verified stored execution). Captures are
+// encoded to BrowserConfig.ScreenshotFormat (JPEG by default, quality 90) and
+// stored under BrowserConfig.SnapshotDir/<format>/.
 type Manager struct {
 	sessions      map[string]*BrowserSession
 	sessionsMutex sync.RWMutex
 	config        BrowserConfig
 	isInitialized bool
 	initMutex     sync.Mutex
+
+	// allocCtx owns the Chrome process shared by every validation. browserCtx is
+	// the first chromedp context on that allocator; per-call tabs derive from it
+	// so that cancelling a tab never tears down the browser itself.
+	allocCtx       context.Context
+	cancelAlloc    context.CancelFunc
+	browserCtx     context.Context
+	cancelBrowser  context.CancelFunc
+	browserStarted bool
+	browserMutex   sync.Mutex
+
+	// browserPID is the Chrome in use, launchedPIDs every Chrome launched and
+	// not reaped yet, see KillOrphans
+	browserPID   int
+	launchedPIDs []int
+
+	// tabs bounds the number of concurrently open tabs
+	tabs chan struct{}
+
+	// sessionReleased wakes AcquireSession callers waiting on a full pool;
+	// stopReaper ends the idle session reaper on Shutdown.
+	sessionReleased chan struct{}
+	stopReaper      chan struct{}
+
+	// creatingSessions counts sessions AcquireSession is starting outside the
+	// lock, which count against MaxSessions; guarded by sessionsMutex
+	creatingSessions int
+	sessionSeq       atomic.Uint64 // makes session IDs unique
+
+	// screenshots maps the SHA-256 of every screenshot written so far to its
+	// path, so identical captures share one file.
+	screenshots   map[[sha256.Size]byte]string
+	screenshotsMu sync.Mutex
+	snapshotSeq   atomic.Uint64 // makes evidence file names unique
+	userAgentSeq  atomic.Uint64 // rotates BrowserConfig.UserAgents
+
+	// onExecution holds the callbacks registered with OnExecution
+	onExecution   []func(ExecutionProof)
+	onExecutionMu sync.RWMutex
+
+	// limiter paces navigations per host, nil without NavigationsPerSecond
+	limiter *hostLimiter
+
+	// log is the Logger set with SetLogger, nil for the default
+	log Logger
+
+	// stats backs Stats
+	stats validationStats
 }
 
+// defaultMaxTabs is used when BrowserConfig.MaxTabs is not set
+const defaultMaxTabs = 10
+
+// defaultSnapshotDir is used when BrowserConfig.SnapshotDir is not set
+const defaultSnapshotDir = "snapshots"
+
+// defaultNavRetryBackoff is the first retry delay when BrowserConfig.NavRetryBackoffMs is not set
+const defaultNavRetryBackoff = 500 * time.Millisecond
+
+// defaultMaxEvidenceLen is used when BrowserConfig.MaxEvidenceLen is not set
+const defaultMaxEvidenceLen = 4096
+
+// evidenceTruncatedMarker ends evidence cut at the MaxEvidenceLen cap
+const evidenceTruncatedMarker = "...[truncated]"
+
+// dialogCollectWindow is how long further dialogs are collected after the first one
+const dialogCollectWindow = 500 * time.Millisecond
+
 // NewManager creates a new browser session manager
 func NewManager(cfg BrowserConfig) *Manager {
 	return &Manager{
-		sessions:      make(map[string]*BrowserSession),
-		config:        cfg,
-		isInitialized: false,
+		sessions:        make(map[string]*BrowserSession),
+		config:          cfg,
+		isInitialized:   false,
+		sessionReleased: make(chan struct{}, 1),
+		screenshots:     make(map[[sha256.Size]byte]string),
+		limiter:         newHostLimiter(cfg.NavigationsPerSecond),
 	}
 }
 
@@ -50,18 +123,86 @@ func (m *Manager) Initialize() error {
 		return nil
 	}
 
-	// Ensure snapshot directories exist
-	_ = os.MkdirAll("snapshots/jpg", 0755)
-	_ = os.MkdirAll("snapshots/svg", 0755)
+	// Snapshot subdirectories are created when the first file is written into
+	// them, so managers that never capture evidence leave the disk untouched
+	if !m.config.ScreenshotToDiskDisabled && m.config.SnapshotRetention > 0 {
+		n, err := m.PurgeSnapshots(time.Duration(m.config.SnapshotRetention) * time.Hour)
+		if n > 0 || err != nil {
+			m.logger().Info("browser: purged old snapshots", "removed", n, "error", err)
+		}
+	}
+
+	// The allocator is created once and reused by every validation. Chrome itself
+	// is launched lazily on the first tab so that a missing binary only affects
+	// browser validation, not the rest of the scan.
+	m.allocCtx, m.cancelAlloc = chromedp.NewExecAllocator(context.Background(), m.allocatorOptions()...)
+	m.browserCtx, m.cancelBrowser = chromedp.NewContext(m.allocCtx)
+	m.browserStarted = false
+
+	maxTabs := m.config.MaxTabs
+	if maxTabs <= 0 {
+		maxTabs = defaultMaxTabs
+	}
+	m.tabs = make(chan struct{}, maxTabs)
+
+	m.stopReaper = make(chan struct{})
+	go m.reapSessions(m.stopReaper)
+
+	if m.config.VerifyChromeOnInit {
+		if err := m.healthCheck(context.Background()); err != nil {
+			close(m.stopReaper)
+			m.stopReaper = nil
+			m.cancelBrowser()
+			m.cancelAlloc()
+			return err
+		}
+	}
 
-	// chromedp uses the system Chrome/Chromium binary. If ChromiumBinaryPath is provided,
-	// chromedp will use it via ExecPath option at runtime when creating contexts.
 	m.isInitialized = true
 	return nil
 }
 
-// helper: create chromedp context with options based on config
-func (m *Manager) newContext(parent context.Context) (context.Context, context.CancelFunc) {
+// HealthCheck confirms that Chrome launches and evaluates JavaScript by opening
+// a tab on about:blank and running a trivial expression.
+func (m *Manager) HealthCheck(ctx context.Context) error {
+	if !m.IsInitialized() {
+		return ErrNotInitialized
+	}
+	return m.healthCheck(ctx)
+}
+
+func (m *Manager) healthCheck(ctx context.Context) error {
+	tabCtx, cancel, err := m.newContext(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	var sum int
+	if err := m.runWithTimeout(tabCtx, chromedp.Navigate("about:blank"), chromedp.Evaluate("1+1", &sum)); err != nil {
+		return fmt.Errorf("browser health check: %w", err)
+	}
+	if sum != 2 {
+		return fmt.Errorf("browser health check: 1+1 evaluated to %d", sum)
+	}
+	return nil
+}
+
+// snapshotDir returns the absolute directory screenshots and page HTML are
+// written to. Relative paths are resolved against the working directory.
+func (m *Manager) snapshotDir() string {
+	dir := m.config.SnapshotDir
+	if dir == "" {
+		dir = defaultSnapshotDir
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// allocatorOptions builds the chromedp exec allocator options from config
+func (m *Manager) allocatorOptions() []chromedp.ExecAllocatorOption {
 	opts := []chromedp.ExecAllocatorOption{
 		chromedp.NoDefaultBrowserCheck,
 		chromedp.Flag("disable-background-networking", true),
@@ -73,6 +214,10 @@ func (m *Manager) newContext(parent context.Context) (context.Context, context.C
 		chromedp.Flag("disable-sync", true),
 		chromedp.Flag("metrics-recording-only", true),
 		chromedp.Flag("enable-automation", true),
+		// keep cross-site iframes in the tab's renderer: chromedp does not route
+		// the events of out-of-process frames, so their dialogs went unnoticed
+		chromedp.Flag("disable-features", "site-per-process,IsolateOrigins"),
+		chromedp.Flag("disable-site-isolation-trials", true),
 	}
 
 	if m.config.HeadlessMode {
@@ -84,131 +229,887 @@ func (m *Manager) newContext(parent context.Context) (context.Context, context.C
 	if m.config.ChromiumBinaryPath != "" {
 		opts = append(opts, chromedp.ExecPath(m.config.ChromiumBinaryPath))
 	}
+	if m.config.ProxyServer != "" {
+		opts = append(opts, chromedp.ProxyServer(m.config.ProxyServer))
+		if m.config.ProxyBypassList != "" {
+			opts = append(opts, chromedp.Flag("proxy-bypass-list", m.config.ProxyBypassList))
+		}
+	}
+
+	return opts
+}
+
+// ensureBrowser launches the shared Chrome process if it is not running yet.
+func (m *Manager) ensureBrowser() error {
+	m.browserMutex.Lock()
+	defer m.browserMutex.Unlock()
+
+	if m.browserStarted {
+		return nil
+	}
+	if err := chromedp.Run(m.browserCtx); err != nil {
+		// a failed launch leaves the context unusable; start over on the next call
+		m.cancelBrowser()
+		m.browserCtx, m.cancelBrowser = chromedp.NewContext(m.allocCtx)
+		return launchError(err)
+	}
+	m.browserStarted = true
+	m.recordBrowserPID()
+	return nil
+}
 
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parent, opts...)
-	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+// newContext opens a new tab on the shared browser. When sessionID names a pooled
+// session the tab is opened in that session's browser context so it shares its
+// cookies. It blocks while maxTabs tabs are already open and gives up when parent
+// is done. The tab emulates the configured viewport or device, see
+// emulatedDevice. The returned cancel func closes the tab and releases its slot.
+func (m *Manager) newContext(parent context.Context, sessionID string) (context.Context, context.CancelFunc, error) {
+	select {
+	case m.tabs <- struct{}{}:
+	case <-parent.Done():
+		return nil, nil, parent.Err()
+	}
 
-	return ctx, func() {
-		cancelCtx()
-		cancelAlloc()
+	if err := m.ensureBrowser(); err != nil {
+		<-m.tabs
+		return nil, nil, err
 	}
+
+	m.browserMutex.Lock()
+	base := m.browserCtx
+	m.browserMutex.Unlock()
+
+	if s, ok := m.getSession(sessionID); ok {
+		base = s.ctx
+	}
+
+	ctx, cancelCtx := chromedp.NewContext(base)
+
+	// propagate cancellation of the caller's context to the tab
+	stop := context.AfterFunc(parent, cancelCtx)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			stop()
+			cancelCtx()
+			<-m.tabs
+		})
+	}
+
+	d, err := emulatedDevice(&m.config)
+	if err == nil && d != nil {
+		err = chromedp.Run(ctx, chromedp.Emulate(d))
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return ctx, cancel, nil
 }
 
 // ValidatePayload navigates to the provided URL which should already include the payload
 // (scanner is responsible for injecting payload into parameters). This function waits
 // for JavaScript dialogs (alert/confirm/prompt) and for a limited time specified in
-// BrowserConfig.WaitForAlertOnlyTime. If execution is detected, a JPG screenshot is
-// taken (quality >=90) and saved to snapshots/jpg/ with filename including target+payload hashes.
+// BrowserConfig.WaitForAlertOnlyTime. With DetectDOMExecution set, a call to the
+// sentinel function with the payload's canary token also counts as execution and is
+// reported as "dom-change". If execution is detected, a JPG screenshot is
+// taken (BrowserConfig.ScreenshotQuality) and saved under BrowserConfig.SnapshotDir with filename including target+payload hashes.
+// url may also be a data: URL carrying the page itself (data:text/html,...), which
+// tests the detection without a server; such pages have no recorded HTTP response.
+// With BrowserConfig.TotalPayloadTimeout set, a validation running longer is
+// aborted with ErrPayloadTimeout. contextStr is parsed with ParseContext, so the
+// proof reports one of the Context values; an unknown one fails the validation.
 func (m *Manager) ValidatePayload(sessionID string, url string, payload string, contextStr string) *ValidationResult {
+	return m.ValidatePayloadCtx(context.Background(), sessionID, url, payload, contextStr)
+}
+
+// ValidatePayloadCtx is like ValidatePayload but bound to ctx: cancelling ctx or
+// reaching its deadline aborts the validation, including the wait for a free tab,
+// and closes its tab. The result then carries ctx.Err(). This lets a scanner stop
+// every in-flight validation at once.
+func (m *Manager) ValidatePayloadCtx(ctx context.Context, sessionID string, url string, payload string, contextStr string) *ValidationResult {
+	return m.validate(ctx, sessionID, url, nil, payload, contextStr, 0)
+}
+
+// ValidatePayloadWithTimeout is like ValidatePayload but waits waitSec seconds
+// for execution instead of BrowserConfig.WaitForAlertOnlyTime, for payloads that
+// fire late such as setTimeout based ones. A waitSec of zero uses the config.
+func (m *Manager) ValidatePayloadWithTimeout(sessionID string, url string, payload string, contextStr string, waitSec int) *ValidationResult {
+	return m.validate(context.Background(), sessionID, url, nil, payload, contextStr, waitSec)
+}
+
+// ValidatePayloadPOST is like ValidatePayload but loads url with a POST request
+// carrying body, for sinks reached through forms. The navigation itself is
+// rewritten through request interception, so the response is rendered as a
+// regular page and dialog detection works unchanged. An empty contentType
+// defaults to application/x-www-form-urlencoded.
+func (m *Manager) ValidatePayloadPOST(sessionID, url string, body string, contentType string, payload, contextStr string) *ValidationResult {
+	return m.validate(context.Background(), sessionID, url, &postRequest{Body: body, ContentType: contentType}, payload, contextStr, 0)
+}
+
+// validate runs a single validation in a new tab. A panic during the validation
+// is returned as ErrValidationPanic and restarts the browser.
+func (m *Manager) validate(parent context.Context, sessionID string, url string, post *postRequest, payload string, contextStr string, waitSec int) (result *ValidationResult) {
 	if !m.IsInitialized() {
 		return &ValidationResult{
 			IsVulnerable:      false,
 			ExecutionDetected: false,
-			Error:             fmt.Errorf("browser not initialized"),
+			Error:             ErrNotInitialized,
 		}
 	}
 
 	start := time.Now()
+	defer m.recoverValidation(&result, start)
+	execContext, err := ParseContext(contextStr)
+	if err != nil {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err}
+	}
+	if post != nil && isLocalDocument(url) {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: errors.New("POST validation needs an http(s) URL")}
+	}
 
-	parent := context.Background()
-	ctx, cancel := m.newContext(parent)
+	if limit := time.Duration(m.config.TotalPayloadTimeout) * time.Second; limit > 0 {
+		var cancelBudget context.CancelFunc
+		parent, cancelBudget = context.WithTimeoutCause(parent, limit, fmt.Errorf("%w: %w", ErrPayloadTimeout, context.DeadlineExceeded))
+		defer cancelBudget()
+	}
+
+	ctx, cancel, err := m.newContext(parent, sessionID)
+	if err != nil {
+		if parent.Err() != nil {
+			err = context.Cause(parent)
+		}
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err, ValidationDuration: time.Since(start)}
+	}
 	defer cancel()
 
-	// channel to receive dialog events
-	dialogCh := make(chan *page.EventJavascriptDialogOpening, 1)
+	router := m.listenTab(ctx)
+	state := newTabState()
+	state.post = post
+	state.waitSec = waitSec
+	router.set(state)
 
-	chromedp.ListenTarget(ctx, func(ev interface{}) {
-		switch e := ev.(type) {
-		case *page.EventJavascriptDialogOpening:
-			select {
-			case dialogCh <- e:
-			default:
+	result = m.validateInTab(ctx, router, state, url, payload, execContext, start)
+	// dialogs raised after the result was decided are dismissed until the tab closes
+	router.set(nil)
+	if parent.Err() != nil && !result.ExecutionDetected {
+		// the tab only sees a cancellation; report the caller's reason, or the
+		// exceeded TotalPayloadTimeout, instead
+		result.Error = context.Cause(parent)
+	}
+	return result
+}
+
+// OnExecution registers fn to be called with the proof of every confirmed
+// execution, e.g. to stream findings into a dashboard while the scan runs.
+// Callbacks run synchronously on the validating goroutine, in registration
+// order, before the validation returns. Validations run concurrently, so fn must
+// be safe for concurrent use and should return quickly.
+func (m *Manager) OnExecution(fn func(ExecutionProof)) {
+	if fn == nil {
+		return
+	}
+	m.onExecutionMu.Lock()
+	defer m.onExecutionMu.Unlock()
+	m.onExecution = append(m.onExecution, fn)
+}
+
+// notifyExecution calls the OnExecution callbacks with proof
+func (m *Manager) notifyExecution(proof ExecutionProof) {
+	m.onExecutionMu.RLock()
+	callbacks := m.onExecution
+	m.onExecutionMu.RUnlock()
+	for _, fn := range callbacks {
+		fn(proof)
+	}
+}
+
+// PayloadItem is a single URL/payload pair validated by ValidatePayloadBatch
+type PayloadItem struct {
+	URL     string `json:"url"`
+	Payload string `json:"payload"`
+	Context string `json:"context"` // see ParseContext
+}
+
+// ValidatePayloadBatch validates several items sequentially in one tab, which is
+// much cheaper than opening a tab per payload. Between items the tab is reset so
+// a dialog raised by one item is never attributed to the next one. Each item
+// gets its own TotalPayloadTimeout budget. Results are returned in the order of
+// items.
+func (m *Manager) ValidatePayloadBatch(sessionID string, items []PayloadItem) []*ValidationResult {
+	results := make([]*ValidationResult, len(items))
+	fail := func(from int, err error) []*ValidationResult {
+		for i := from; i < len(items); i++ {
+			results[i] = &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err}
+		}
+		return results
+	}
+	if !m.IsInitialized() {
+		return fail(0, ErrNotInitialized)
+	}
+
+	parent := context.Background()
+	ctx, cancel, err := m.newContext(parent, sessionID)
+	if err != nil {
+		return fail(0, err)
+	}
+	defer cancel()
+
+	router := m.listenTab(ctx)
+	for i, item := range items {
+		start := time.Now()
+		if i > 0 {
+			if err := m.resetTab(ctx, router); err != nil {
+				return fail(i, err)
 			}
 		}
+		results[i] = m.validateBatchItem(ctx, router, item, start)
+	}
+	return results
+}
+
+// validateBatchItem validates one item of ValidatePayloadBatch in the batch's
+// tab. Like validate for single payloads, it is cut at TotalPayloadTimeout and a
+// panic is returned as ErrValidationPanic, so one item cannot stall or crash the
+// batch. After a panic the browser restarts and the remaining items fail to
+// reset the tab.
+func (m *Manager) validateBatchItem(ctx context.Context, router *tabRouter, item PayloadItem, start time.Time) (result *ValidationResult) {
+	defer m.recoverValidation(&result, start)
+	execContext, err := ParseContext(item.Context)
+	if err != nil {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err}
+	}
+	if limit := time.Duration(m.config.TotalPayloadTimeout) * time.Second; limit > 0 {
+		var cancelBudget context.CancelFunc
+		ctx, cancelBudget = context.WithTimeoutCause(ctx, limit, fmt.Errorf("%w: %w", ErrPayloadTimeout, context.DeadlineExceeded))
+		defer cancelBudget()
+	}
+
+	state := newTabState()
+	router.set(state)
+	result = m.validateInTab(ctx, router, state, item.URL, item.Payload, execContext, start)
+	if ctx.Err() != nil && !result.ExecutionDetected {
+		result.Error = context.Cause(ctx)
+	}
+	return result
+}
+
+// ValidateURLs validates urls concurrently, each in its own tab, using at most
+// concurrency workers. The number of open tabs is still bounded by MaxTabs, so a
+// concurrency above it only queues work. A concurrency below 1 uses MaxTabs
+// workers. payload and contextStr are passed to ValidatePayload for every url.
+// Results are returned in the order of urls.
+func (m *Manager) ValidateURLs(urls []string, payload string, contextStr string, concurrency int) []*ValidationResult {
+	results := make([]*ValidationResult, len(urls))
+	m.ValidateURLsFunc(urls, payload, contextStr, concurrency, func(i int, result *ValidationResult) {
+		results[i] = result
 	})
+	return results
+}
 
+// ValidateURLsFunc is ValidateURLs passing each result to fn, with the index of
+// its url, as soon as that url is validated. fn is called by one worker at a
+// time and all calls have returned when ValidateURLsFunc does.
+func (m *Manager) ValidateURLsFunc(urls []string, payload string, contextStr string, concurrency int, fn func(i int, result *ValidationResult)) {
+	if !m.IsInitialized() {
+		for i := range urls {
+			fn(i, &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: ErrNotInitialized})
+		}
+		return
+	}
+	if concurrency < 1 {
+		concurrency = cap(m.tabs)
+	}
+	if concurrency > len(urls) {
+		concurrency = len(urls)
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := m.ValidatePayload("", urls[i], payload, contextStr)
+				mu.Lock()
+				fn(i, result)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// validateInTab navigates an already opened tab to url and waits for execution.
+// Events are read from state, which the caller has attached to the tab's router;
+// it is detached only while PreActions run.
+func (m *Manager) validateInTab(ctx context.Context, router *tabRouter, state *tabState, url string, payload string, execContext Context, start time.Time) (result *ValidationResult) {
 	// navigate
-	navCtx, navCancel := context.WithTimeout(ctx, time.Duration(m.config.Timeout)*time.Second)
-	defer navCancel()
-	var navErr error
-	navErr = chromedp.Run(navCtx, chromedp.Navigate(url))
+	canary := CanaryToken(payload)
+	if m.config.StrictDialogMatch {
+		state.expectDialog(canary)
+	}
+	var scriptID page.ScriptIdentifier
+	navErr := m.runWithTimeout(ctx, m.navigateTasks(url, canary, &scriptID, state.hasPOST()))
+	if scriptID != "" {
+		// the canary script must not leak into later navigations of this tab
+		defer chromedp.Run(ctx, page.RemoveScriptToEvaluateOnNewDocument(scriptID))
+	}
+	if navErr == nil && len(m.config.PreActions) > 0 {
+		// like the injection of VerifyStoredXSS, pre-actions run detached: their
+		// dialogs are dismissed and they leave a pending POST override alone
+		router.set(nil)
+		navErr = m.runPreActions(ctx)
+		router.set(state)
+	}
+	var retries int
+	if navErr == nil {
+		retries, navErr = m.navigate(ctx, state, url)
+	}
+	defer func() {
+		if result != nil {
+			result.NavRetries = retries
+			m.stats.record(result)
+		}
+	}()
 	if navErr != nil {
-		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: navErr, ValidationDuration: time.Since(start)}
+		m.stats.navFailure(navErr)
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: navigationError(navErr), ValidationDuration: time.Since(start)}
+	}
+
+	// let frameworks hydrate before the wait window starts; dialogs raised in the
+	// meantime are buffered by the router and picked up below
+	if settle := time.Duration(m.config.PostNavigateWaitMs) * time.Millisecond; settle > 0 {
+		select {
+		case <-time.After(settle):
+		case <-ctx.Done():
+		}
 	}
 
-	// wait for dialog up to configured WaitForAlertOnlyTime seconds
+	// wait for dialog up to configured WaitForAlertOnlyTime seconds, unless the
+	// caller asked for a different window
 	waitSec := m.config.WaitForAlertOnlyTime
+	if state.waitSec > 0 {
+		waitSec = state.waitSec
+	}
 	if waitSec <= 0 {
 		waitSec = 5
 	}
 
-	select {
-	case dlg := <-dialogCh:
-		// Execution confirmed - TAKE SCREENSHOT
-		proof := ExecutionProof{
-			PayloadSHA256:    fmt.Sprintf("%x", sha256.Sum256([]byte(payload))),
-			ExecutionType:    dialogTypeFromString(dlg.Type.String()),
-			ExecutedAt:       time.Now(),
-			Evidence:         dlg.Message,
-			PageURL:          url,
-			PageTitle:        "",
-			ExecutionContext: contextStr,
+	// optionally watch for the DOM canary sentinel alongside dialogs
+	var domCh <-chan string
+	if m.config.DetectDOMExecution {
+		pollCtx, pollCancel := context.WithCancel(ctx)
+		defer pollCancel()
+		domCh = pollCanary(pollCtx, canary)
+	}
+
+	// With WaitForNetworkIdle the wait window only starts once the page stopped
+	// loading and is extended while late requests are still in flight, each phase
+	// bounded by Timeout.
+	window := time.Duration(waitSec) * time.Second
+	idleLimit := time.Duration(m.config.Timeout) * time.Second
+	windowStarted := !m.config.WaitForNetworkIdle
+	settleBy := time.Now().Add(idleLimit)
+	first := window
+	if !windowStarted {
+		first = 0
+	}
+	timer := time.NewTimer(first)
+	defer timer.Stop()
+
+	// the wait window also holds until the UI gate passed; dialogs raised while
+	// it runs are still picked up
+	var gateCh <-chan error
+	if gate := m.gateTasks(); len(gate) > 0 {
+		timer.Stop()
+		ch := make(chan error, 1)
+		gateCtx, gateCancel := context.WithCancel(ctx)
+		defer gateCancel()
+		go func() { ch <- m.runWithTimeout(gateCtx, gate) }()
+		gateCh = ch
+	}
+
+	for {
+		select {
+		case err := <-gateCh:
+			gateCh = nil
+			if err != nil {
+				return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err, PageTitle: m.pageTitle(ctx), ValidationDuration: time.Since(start)}
+			}
+			settleBy = time.Now().Add(idleLimit)
+			timer.Reset(first)
+		case dlg := <-state.dialogCh:
+			// Execution confirmed - TAKE SCREENSHOT
+			proof := ExecutionProof{
+				PayloadSHA256:    fmt.Sprintf("%x", sha256.Sum256([]byte(payload))),
+				ExecutionType:    dialogTypeFromString(dlg.Type.String()),
+				ExecutedAt:       time.Now(),
+				Evidence:         dialogEvidence(dlg),
+				PageURL:          url,
+				PageTitle:        "",
+				ExecutionContext: string(execContext),
+			}
+			if dlg.Type == page.DialogTypePrompt {
+				proof.PromptDefault = dlg.DefaultPrompt
+			}
+			if isSubframe(ctx, dlg.FrameID) {
+				proof.FrameURL = dlg.URL
+			}
+			m.captureProof(ctx, &proof, state, url, payload, true)
+
+			// payloads often raise several dialogs; give them a moment and count them all
+			select {
+			case <-time.After(dialogCollectWindow):
+			case <-ctx.Done():
+			}
+			proof.DialogCount, proof.Evidence = state.dialogSummary()
+			proof.Evidence = truncateEvidence(proof.Evidence, m.maxEvidenceLen())
+			proof.StackTrace, _ = state.stackTraces()
+			m.notifyExecution(proof)
+
+			return &ValidationResult{
+				IsVulnerable:       true,
+				ExecutionDetected:  true,
+				ExecutionProofs:    []ExecutionProof{proof},
+				PageTitle:          proof.PageTitle,
+				ValidationDuration: time.Since(start),
+				Engine:             EngineChromium,
+			}
+		case token := <-domCh:
+			// sentinel invoked with our canary - execution confirmed without a dialog
+			proof := ExecutionProof{
+				PayloadSHA256:    fmt.Sprintf("%x", sha256.Sum256([]byte(payload))),
+				ExecutionType:    "dom-change",
+				ExecutedAt:       time.Now(),
+				Evidence:         token,
+				PageURL:          url,
+				ExecutionContext: string(execContext),
+				ShadowHost:       shadowHost(ctx),
+			}
+			m.captureProof(ctx, &proof, state, url, payload, false)
+			_, proof.StackTrace = state.stackTraces()
+			m.notifyExecution(proof)
+
+			return &ValidationResult{
+				IsVulnerable:       true,
+				ExecutionDetected:  true,
+				ExecutionProofs:    []ExecutionProof{proof},
+				PageTitle:          proof.PageTitle,
+				ValidationDuration: time.Since(start),
+				Engine:             EngineChromium,
+			}
+		case <-ctx.Done():
+			// interrupted (e.g. Ctrl+C or parent deadline) while waiting for execution
+			return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: ctx.Err(), ValidationDuration: time.Since(start)}
+		case <-timer.C:
+			if m.config.WaitForNetworkIdle {
+				now := time.Now()
+				if d := state.network.idleIn(networkIdleWindow); d > 0 && now.Before(settleBy) {
+					timer.Reset(min(d, settleBy.Sub(now)))
+					continue
+				}
+				if !windowStarted {
+					windowStarted = true
+					settleBy = now.Add(window + idleLimit)
+					timer.Reset(window)
+					continue
+				}
+			}
+			// No execution detected; the title still helps triage (error page, WAF block page)
+			return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, PageTitle: m.pageTitle(ctx), ValidationDuration: time.Since(start)}
 		}
+	}
+}
 
-		// take screenshot (full page); chromedp returns PNG bytes
-		var pngBuf []byte
-		if err := chromedp.Run(ctx, chromedp.FullScreenshot(&pngBuf, 90)); err == nil {
-			// convert PNG to JPEG and save
-			jpgBytes, err := convertPNGtoJPG(pngBuf, 95)
+// navigate loads url, retrying transient failures up to NavRetries times with
+// an exponential backoff starting at NavRetryBackoffMs. Permanent errors such as
+// an unresolvable host are not retried. It returns the number of retries made.
+func (m *Manager) navigate(ctx context.Context, state *tabState, url string) (int, error) {
+	post := state.pendingPOST()
+	backoff := time.Duration(m.config.NavRetryBackoffMs) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultNavRetryBackoff
+	}
+
+	retries := 0
+	for {
+		if err := m.limiter.wait(ctx, url); err != nil {
+			return retries, err
+		}
+		err := m.runWithTimeout(ctx, chromedp.Navigate(url))
+		if err == nil || retries >= m.config.NavRetries || isPermanentNavError(err) || ctx.Err() != nil {
+			return retries, err
+		}
+		select {
+		case <-time.After(backoff << retries):
+		case <-ctx.Done():
+			return retries, err
+		}
+		retries++
+		// the failed attempt consumed the POST override
+		state.setPOST(post)
+	}
+}
+
+// navigateTasks returns the actions that prepare a tab for loading url. Configured
+// extra headers are applied through the Network domain first so they are sent
+// with the initial request and every redirect that follows it. intercept forces
+// request interception, e.g. to rewrite the navigation into a POST.
+func (m *Manager) navigateTasks(url string, canary string, scriptID *page.ScriptIdentifier, intercept bool) chromedp.Tasks {
+	tasks := chromedp.Tasks{network.Enable()}
+	tasks = append(tasks, m.interceptTasks(intercept)...)
+	if len(m.config.Headers) > 0 {
+		headers := make(network.Headers, len(m.config.Headers))
+		for k, v := range m.config.Headers {
+			headers[k] = v
+		}
+		tasks = append(tasks, network.SetExtraHTTPHeaders(headers))
+	}
+	if ua := m.nextUserAgent(); ua != "" {
+		tasks = append(tasks, emulation.SetUserAgentOverride(ua))
+	}
+	// local documents have an opaque origin that cookies cannot be scoped to
+	if len(m.config.Cookies) > 0 && !isLocalDocument(url) {
+		tasks = append(tasks, network.SetCookies(cookieParams(m.config.Cookies, url)))
+	}
+	if m.config.DetectDOMExecution || m.config.StrictDialogMatch || m.config.CaptureStackTrace {
+		tasks = append(tasks, installCanary(canary, m.config.CaptureStackTrace, scriptID))
+	}
+	return tasks
+}
+
+// gateTasks returns the UI interaction a validation waits for before its dialog
+// window: a click on each ClickSelectors element in order, then WaitForSelector
+// becoming visible. Errors name the selector that failed.
+func (m *Manager) gateTasks() chromedp.Tasks {
+	var tasks chromedp.Tasks
+	for _, sel := range m.config.ClickSelectors {
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			if err := chromedp.Click(sel, chromedp.NodeVisible).Do(ctx); err != nil {
+				return fmt.Errorf("click %q: %w", sel, err)
+			}
+			return nil
+		}))
+	}
+	if sel := m.config.WaitForSelector; sel != "" {
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			if err := chromedp.WaitVisible(sel).Do(ctx); err != nil {
+				return fmt.Errorf("wait for selector %q: %w", sel, err)
+			}
+			return nil
+		}))
+	}
+	return tasks
+}
+
+// isSubframe reports whether frame is an iframe of the tab rather than its top
+// document, whose frame ID is the ID of the tab's target
+func isSubframe(ctx context.Context, frame cdp.FrameID) bool {
+	c := chromedp.FromContext(ctx)
+	return frame != "" && c != nil && c.Target != nil && string(frame) != string(c.Target.TargetID)
+}
+
+// nextUserAgent returns the user agent for the next validation: the next entry
+// of UserAgents, else UserAgent, else "" to keep Chrome's own
+func (m *Manager) nextUserAgent() string {
+	if n := len(m.config.UserAgents); n > 0 {
+		return m.config.UserAgents[(m.userAgentSeq.Add(1)-1)%uint64(n)]
+	}
+	return m.config.UserAgent
+}
+
+// isLocalDocument reports whether url is rendered by the browser itself, like
+// data:text/html,<script>alert(1)</script> or about:blank, rather than fetched.
+// Such pages produce no document response and no network events, but dialog
+// and DOM execution detection work the same, which allows testing payloads
+// without a server.
+func isLocalDocument(url string) bool {
+	scheme, _, ok := strings.Cut(url, ":")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(scheme)) {
+	case "data", "about":
+		return true
+	}
+	return false
+}
+
+// runWithTimeout runs actions on ctx bounded by the configured navigation timeout
+func (m *Manager) runWithTimeout(ctx context.Context, actions ...chromedp.Action) error {
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(m.config.Timeout)*time.Second)
+	defer cancel()
+	return chromedp.Run(runCtx, actions...)
+}
+
+// cookieParams converts configured cookies to CDP parameters. Cookies with a
+// domain are stored as domain cookies (leading dot) so they match subdomains of
+// the target; cookies without a domain are bound to url.
+func cookieParams(cookies []Cookie, url string) []*network.CookieParam {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		if p.Path == "" {
+			p.Path = "/"
+		}
+		if c.Domain != "" {
+			p.Domain = "." + strings.TrimPrefix(c.Domain, ".")
+		} else {
+			p.URL = url
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// captureProof fills the page HTML, screenshot, page title, console output and the
+// HTTP request and response of the document of a confirmed execution. When dialogOpen is set the
+// dialog is kept open for the screenshot and accepted right after, so scripts
+// blocked by it can continue.
+func (m *Manager) captureProof(ctx context.Context, proof *ExecutionProof, state *tabState, url string, payload string, dialogOpen bool) {
+	baseName := m.snapshotBaseName(url, payload)
+
+	if req := state.request(); req != nil {
+		proof.RequestMethod = req.Method
+		proof.RequestURL = req.URL
+		proof.RequestHeaders = req.Headers
+		proof.RequestBody = req.Body
+	}
+	if doc := state.document(); doc != nil {
+		proof.ResponseStatus = doc.Status
+		proof.ResponseHeaders = doc.Headers
+		_ = m.runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			body, err := network.GetResponseBody(doc.RequestID).Do(ctx)
 			if err == nil {
-				// filename: targethash_payloadhash_timestamp.jpg
-				targetHash := fmt.Sprintf("%x", sha256.Sum256([]byte(url)))
-				payloadHash := fmt.Sprintf("%x", sha256.Sum256([]byte(payload)))
-				fname := fmt.Sprintf("%s_%s_%d.jpg", targetHash[:12], payloadHash[:12], time.Now().Unix())
-				outPath := filepath.Join("snapshots", "jpg", fname)
-				if err := ioutil.WriteFile(outPath, jpgBytes, 0644); err == nil {
-					proof.ScreenshotPath = outPath
-					proof.ScreenshotData = []byte(base64.StdEncoding.EncodeToString(jpgBytes))
+				proof.ResponseBody = string(body)
+			}
+			return err
+		}))
+	}
+
+	// the rendered DOM is taken first, as close to the moment of execution as possible
+	if m.config.CapturePageHTML {
+		var html string
+		if err := m.runWithTimeout(ctx, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err == nil {
+			proof.PageHTML = html
+			if !m.config.ScreenshotToDiskDisabled {
+				outPath := filepath.Join(m.snapshotDir(), "html", baseName+".html")
+				if err := writeEvidenceFile(outPath, []byte(html)); err == nil {
+					proof.HTMLPath = outPath
 				}
 			}
 		}
+	}
 
-		// fill title if possible
-		var title string
-		_ = chromedp.Run(ctx, chromedp.Title(&title))
-		proof.PageTitle = title
+	// take screenshot (element or full page)
+	format := normalizeScreenshotFormat(m.config.ScreenshotFormat)
+	quality := m.screenshotQuality()
+	if pngBuf, err := m.takeScreenshot(ctx, format, quality); err == nil {
+		// the lossless capture as taken, before downscaling and re-encoding
+		if m.config.KeepOriginalPNG && !m.config.ScreenshotToDiskDisabled && bytes.HasPrefix(pngBuf, pngMagic) {
+			if path, err := m.writeScreenshot(filepath.Join(m.snapshotDir(), FormatPNG, baseName+".png"), pngBuf); err == nil {
+				proof.OriginalPNGPath = path
+			}
+		}
+		// re-encode to the configured format and save
+		if limited, err := m.limitScreenshot(pngBuf); err == nil {
+			pngBuf = limited
+		}
+		imgBytes, ext, err := m.encodeScreenshot(pngBuf, format, quality)
+		if err == nil && m.config.ScreenshotToDiskDisabled {
+			proof.ScreenshotData = imgBytes
+		} else if err == nil {
+			// filename: targethash_payloadhash_timestamp.<ext>
+			fname := baseName + "." + ext
+			outPath := filepath.Join(m.snapshotDir(), ext, fname)
+			if path, err := m.writeScreenshot(outPath, imgBytes); err == nil {
+				proof.ScreenshotPath = path
+				proof.ScreenshotData = imgBytes
+			}
+		}
+	}
 
-		return &ValidationResult{
-			IsVulnerable:       true,
-			ExecutionDetected:  true,
-			ExecutionProofs:    []ExecutionProof{proof},
-			ValidationDuration: time.Since(start),
+	if dialogOpen {
+		_ = m.runWithTimeout(ctx, acceptDialog())
+	}
+
+	// scripts cannot be evaluated while a dialog is open, so the annotated SVG
+	// has to wait until it was accepted. It only exists as a file.
+	if m.config.CaptureAnnotatedSVG && !m.config.ScreenshotToDiskDisabled {
+		if svg, err := m.annotatedSVG(ctx, m.config.ScreenshotSelector, payload); err == nil {
+			outPath := filepath.Join(m.snapshotDir(), "svg", baseName+".svg")
+			if err := writeEvidenceFile(outPath, svg); err == nil {
+				proof.SVGPath = outPath
+			}
 		}
-	case <-time.After(time.Duration(waitSec) * time.Second):
-		// No execution detected
-		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, ValidationDuration: time.Since(start)}
 	}
+
+	// fill title and location if possible
+	proof.PageTitle = m.pageTitle(ctx)
+	proof.FinalURL = m.pageLocation(ctx)
+
+	proof.ConsoleLogs, proof.ConsoleErrors = state.console.snapshot()
+}
+
+// pageTitle returns the title of the tab, or an empty string when it cannot be
+// read (e.g. the context was already cancelled).
+func (m *Manager) pageTitle(ctx context.Context) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	var title string
+	if err := m.runWithTimeout(ctx, chromedp.Title(&title)); err != nil {
+		return ""
+	}
+	return truncateEvidence(title, m.maxEvidenceLen())
 }
 
-// VerifyStoredXSS revisits the URL to check for stored payload execution. It opens a fresh
-// browser context and waits for dialogs similarly to ValidatePayload.
-func (m *Manager) VerifyStoredXSS(url string, sessionID string) *ValidationResult {
-	// For stored XSS, behavior is similar: navigate and wait for dialogs
-	return m.ValidatePayload(sessionID, url, "[stored-check]", "stored")
+// pageLocation returns the URL the tab ended up on after redirects, or an empty
+// string when it cannot be read
+func (m *Manager) pageLocation(ctx context.Context) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	var loc string
+	if err := m.runWithTimeout(ctx, chromedp.Location(&loc)); err != nil {
+		return ""
+	}
+	return loc
 }
 
-// convertPNGtoJPG converts a PNG image bytes to JPEG bytes with given quality (0-100).
-func convertPNGtoJPG(pngBytes []byte, quality int) ([]byte, error) {
-	img, _, err := image.Decode(bytes.NewReader(pngBytes))
+// maxEvidenceLen returns the configured evidence cap, or -1 for none
+func (m *Manager) maxEvidenceLen() int {
+	switch {
+	case m.config.MaxEvidenceLen < 0:
+		return -1
+	case m.config.MaxEvidenceLen == 0:
+		return defaultMaxEvidenceLen
+	}
+	return m.config.MaxEvidenceLen
+}
+
+// truncateEvidence shortens s to at most limit bytes, cut at a rune boundary and
+// ending in evidenceTruncatedMarker. A negative limit keeps s whole.
+func truncateEvidence(s string, limit int) string {
+	if limit < 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit - len(evidenceTruncatedMarker)
+	if cut <= 0 {
+		return evidenceTruncatedMarker[:limit]
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + evidenceTruncatedMarker
+}
+
+// snapshotBaseName builds the evidence file name (without extension) for a
+// validation: target and payload hashes, a nanosecond timestamp and a sequence
+// number, so concurrent validations of the same URL and payload never share a name.
+func (m *Manager) snapshotBaseName(url string, payload string) string {
+	targetHash := fmt.Sprintf("%x", sha256.Sum256([]byte(url)))
+	payloadHash := fmt.Sprintf("%x", sha256.Sum256([]byte(payload)))
+	return fmt.Sprintf("%s_%s_%d_%d", targetHash[:12], payloadHash[:12], time.Now().UnixNano(), m.snapshotSeq.Add(1))
+}
+
+// DryRunValidate reports what ValidatePayload would do for url and payload
+// without starting Chrome: the URL that would be loaded, the target and payload
+// hashes, the canary and the evidence file names. ExecutionDetected is always
+// false. It works on a Manager that was never initialized, e.g. in CI without
+// Chrome. An unparsable url is reported in Error.
+func (m *Manager) DryRunValidate(url string, payload string) *ValidationResult {
+	start := time.Now()
+	if _, err := neturl.Parse(url); err != nil {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err, ValidationDuration: time.Since(start)}
+	}
+
+	baseName := m.snapshotBaseName(url, payload)
+	ext := encodedScreenshotFormat(m.config.ScreenshotFormat)
+	info := &DryRunInfo{
+		URL:           url,
+		TargetSHA256:  fmt.Sprintf("%x", sha256.Sum256([]byte(url))),
+		PayloadSHA256: fmt.Sprintf("%x", sha256.Sum256([]byte(payload))),
+		Canary:        CanaryToken(payload),
+		SnapshotName:  baseName,
+	}
+	if !m.config.ScreenshotToDiskDisabled {
+		info.ScreenshotPath = filepath.Join(m.snapshotDir(), ext, baseName+"."+ext)
+		if m.config.CapturePageHTML {
+			info.HTMLPath = filepath.Join(m.snapshotDir(), "html", baseName+".html")
+		}
+		if m.config.CaptureAnnotatedSVG {
+			info.SVGPath = filepath.Join(m.snapshotDir(), "svg", baseName+".svg")
+		}
+	}
+	return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, DryRun: info, ValidationDuration: time.Since(start)}
+}
+
+// VerifyStoredXSS checks for stored payload execution across two pages. It opens
+// a fresh tab and loads injectURL to submit the payload, e.g. a comment endpoint
+// taking it in the query, then loads viewURL, the page rendering the stored
+// content, in the same tab and waits for dialogs similarly to ValidatePayload.
+// Dialogs raised while injecting are dismissed without being counted, so only
+// execution on the view page confirms the finding. An empty viewURL revisits
+// injectURL. payload is the stored payload; it is hashed into the proof and the
+// snapshot file names and keys the canary like in ValidatePayload.
+func (m *Manager) VerifyStoredXSS(injectURL, viewURL, payload, sessionID string) *ValidationResult {
+	if !m.IsInitialized() {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: ErrNotInitialized}
+	}
+	if viewURL == "" {
+		viewURL = injectURL
+	}
+
+	start := time.Now()
+	ctx, cancel, err := m.newContext(context.Background(), sessionID)
 	if err != nil {
-		return nil, err
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err, ValidationDuration: time.Since(start)}
 	}
-	var buf bytes.Buffer
-	opts := &jpeg.Options{Quality: quality}
-	if err := jpeg.Encode(&buf, img, opts); err != nil {
-		return nil, err
+	defer cancel()
+
+	// no state is attached while injecting, so its dialogs are dismissed
+	router := m.listenTab(ctx)
+	if err := m.limiter.wait(ctx, injectURL); err != nil {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: err, ValidationDuration: time.Since(start)}
 	}
-	return buf.Bytes(), nil
+	var scriptID page.ScriptIdentifier
+	err = m.runWithTimeout(ctx, m.navigateTasks(injectURL, CanaryToken(payload), &scriptID, false), chromedp.Navigate(injectURL))
+	if scriptID != "" {
+		_ = chromedp.Run(ctx, page.RemoveScriptToEvaluateOnNewDocument(scriptID))
+	}
+	if err != nil {
+		return &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: navigationError(err), ValidationDuration: time.Since(start)}
+	}
+
+	state := newTabState()
+	router.set(state)
+	result := m.validateInTab(ctx, router, state, viewURL, payload, ContextStored, start)
+	router.set(nil)
+	return result
+}
+
+// dialogEvidence returns the dialog message; for prompts the default value is
+// appended because it often echoes the payload.
+func dialogEvidence(dlg *page.EventJavascriptDialogOpening) string {
+	if dlg.Type == page.DialogTypePrompt && dlg.DefaultPrompt != "" {
+		return fmt.Sprintf("%s (default: %s)", dlg.Message, dlg.DefaultPrompt)
+	}
+	return dlg.Message
 }
 
 // dialogTypeFromString maps CDP dialog type strings to ExecutionType values
@@ -228,27 +1129,52 @@ func dialogTypeFromString(s string) string {
 // CaptureScreenshot converts the current page to JPEG and returns bytes. Only used after execution confirmation.
 func (m *Manager) CaptureScreenshot(sessionID string) ([]byte, error) {
 	if !m.IsInitialized() {
-		return nil, fmt.Errorf("browser not initialized")
+		return nil, ErrNotInitialized
 	}
 	parent := context.Background()
-	ctx, cancel := m.newContext(parent)
+	ctx, cancel, err := m.newContext(parent, sessionID)
+	if err != nil {
+		return nil, err
+	}
 	defer cancel()
 
 	var pngBuf []byte
-	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&pngBuf, 90)); err != nil {
+	quality := m.screenshotQuality()
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&pngBuf, quality)); err != nil {
 		return nil, err
 	}
-	jpg, err := convertPNGtoJPG(pngBuf, 95)
+	if pngBuf, err = m.limitScreenshot(pngBuf); err != nil {
+		return nil, err
+	}
+	jpg, err := convertPNGtoJPG(pngBuf, quality)
 	if err != nil {
 		return nil, err
 	}
 	return jpg, nil
 }
 
-// Shutdown gracefully closes any resources (no-op for chromedp contexts created per call)
+// Shutdown closes the shared browser and its allocator. Tabs that are still open
+// are cancelled along with it.
 func (m *Manager) Shutdown() error {
 	m.initMutex.Lock()
 	defer m.initMutex.Unlock()
+
+	if m.stopReaper != nil {
+		close(m.stopReaper)
+		m.stopReaper = nil
+	}
+	m.closeSessions()
+
+	m.browserMutex.Lock()
+	if m.cancelBrowser != nil {
+		m.cancelBrowser()
+	}
+	if m.cancelAlloc != nil {
+		m.cancelAlloc()
+	}
+	m.browserStarted = false
+	m.browserMutex.Unlock()
+
 	m.isInitialized = false
 	return nil
 }
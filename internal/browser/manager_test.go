@@ -0,0 +1,1175 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestManager_InitializeShutdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   BrowserConfig
+		wantTabs int
+	}{
+		{
+			name:     "default max tabs",
+			config:   BrowserConfig{HeadlessMode: true},
+			wantTabs: defaultMaxTabs,
+		},
+		{
+			name:     "custom max tabs",
+			config:   BrowserConfig{HeadlessMode: true, MaxTabs: 3},
+			wantTabs: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.SnapshotDir = t.TempDir()
+			m := NewManager(tt.config)
+			if err := m.Initialize(); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			if !m.IsInitialized() {
+				t.Errorf("IsInitialized() = false after Initialize()")
+			}
+			if got := cap(m.tabs); got != tt.wantTabs {
+				t.Errorf("cap(tabs) = %d, want %d", got, tt.wantTabs)
+			}
+			allocCtx := m.allocCtx
+			if err := m.Shutdown(); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+			if allocCtx.Err() == nil {
+				t.Errorf("allocator context was not cancelled by Shutdown()")
+			}
+			if m.IsInitialized() {
+				t.Errorf("IsInitialized() = true after Shutdown()")
+			}
+		})
+	}
+}
+
+func TestManager_SnapshotDir(t *testing.T) {
+	wd := t.TempDir()
+	t.Chdir(wd)
+
+	if got, want := NewManager(BrowserConfig{}).snapshotDir(), filepath.Join(wd, "snapshots"); got != want {
+		t.Errorf("default snapshotDir() = %q, want %q", got, want)
+	}
+
+	root := filepath.Join(t.TempDir(), "out")
+	m := NewManager(BrowserConfig{SnapshotDir: root, ScreenshotFormat: "png", CapturePageHTML: true})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer m.Shutdown()
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("Initialize() created the snapshot directory before any evidence was captured")
+	}
+
+	// subdirectories are created with their first file
+	path, err := m.writeScreenshot(filepath.Join(root, FormatPNG, "shot.png"), pngMagic)
+	if err != nil {
+		t.Fatalf("writeScreenshot() error = %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("screenshot not written to %s: %v", path, err)
+	}
+	if _, err := os.Stat(filepath.Join(wd, "snapshots")); !os.IsNotExist(err) {
+		t.Errorf("snapshots/ created in the working directory despite SnapshotDir")
+	}
+}
+
+func TestManager_ValidatePayloadKeepOriginalPNG(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{KeepOriginalPNG: true})
+	res := m.ValidatePayload("", "data:text/html,<script>alert(1)</script>", "<script>alert(1)</script>", "html")
+	if !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	proof := res.ExecutionProofs[0]
+	if filepath.Ext(proof.ScreenshotPath) != ".jpg" || filepath.Base(filepath.Dir(proof.OriginalPNGPath)) != "png" {
+		t.Errorf("ScreenshotPath, OriginalPNGPath = %q, %q, want a JPEG and a PNG", proof.ScreenshotPath, proof.OriginalPNGPath)
+	}
+	if data, err := os.ReadFile(proof.OriginalPNGPath); err != nil || !bytes.HasPrefix(data, pngMagic) {
+		t.Errorf("original PNG not written: %v", err)
+	}
+}
+
+func TestManager_HealthCheck(t *testing.T) {
+	t.Run("not initialized", func(t *testing.T) {
+		m := NewManager(BrowserConfig{})
+		if err := m.HealthCheck(context.Background()); !errors.Is(err, ErrNotInitialized) {
+			t.Errorf("HealthCheck() error = %v, want %v", err, ErrNotInitialized)
+		}
+	})
+
+	t.Run("missing binary fails Initialize", func(t *testing.T) {
+		m := NewManager(BrowserConfig{
+			SnapshotDir:        t.TempDir(),
+			HeadlessMode:       true,
+			Timeout:            5,
+			ChromiumBinaryPath: "/nonexistent/chrome",
+			VerifyChromeOnInit: true,
+		})
+		if err := m.Initialize(); !errors.Is(err, ErrChromeNotFound) {
+			t.Errorf("Initialize() error = %v, want %v", err, ErrChromeNotFound)
+		}
+		if m.IsInitialized() {
+			t.Errorf("IsInitialized() = true after a failed health check")
+		}
+	})
+
+	t.Run("running browser", func(t *testing.T) {
+		m := newTestManager(t, BrowserConfig{VerifyChromeOnInit: true})
+		if err := m.HealthCheck(context.Background()); err != nil {
+			t.Errorf("HealthCheck() error = %v", err)
+		}
+	})
+}
+
+func TestManager_SessionPool(t *testing.T) {
+	m := NewManager(BrowserConfig{HeadlessMode: true, MaxSessions: 1, SnapshotDir: t.TempDir()})
+	if _, err := m.AcquireSession(context.Background()); err == nil {
+		t.Errorf("AcquireSession() on uninitialized manager should fail")
+	}
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer m.Shutdown()
+
+	// seed the pool with a leased session so no Chrome is needed
+	leased := &BrowserSession{ID: "session_test", Active: true, ctx: context.Background(), cancel: func() {}}
+	m.addSession(leased)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := m.AcquireSession(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AcquireSession() on exhausted pool error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	m.ReleaseSession(leased.ID)
+	got, err := m.AcquireSession(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireSession() error = %v", err)
+	}
+	if got.ID != leased.ID || !got.Active {
+		t.Errorf("AcquireSession() did not reuse the released session")
+	}
+
+	// unknown IDs are ignored
+	m.ReleaseSession("missing")
+}
+
+func TestManager_AcquireSessionConcurrent(t *testing.T) {
+	m := NewManager(BrowserConfig{HeadlessMode: true, MaxSessions: 2, SnapshotDir: t.TempDir()})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer m.Shutdown()
+	for _, id := range []string{"session_a", "session_b"} {
+		m.addSession(&BrowserSession{ID: id, ctx: context.Background(), cancel: func() {}})
+	}
+
+	// leases are read while other workers release and re-lease the same
+	// sessions; run with -race to catch shared state
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s, err := m.AcquireSession(context.Background())
+				if err != nil {
+					t.Errorf("AcquireSession() error = %v", err)
+					return
+				}
+				m.ReleaseSession(s.ID)
+				if !s.Active || s.LastUsed.IsZero() {
+					t.Errorf("AcquireSession() = %+v, want an active lease", s)
+				}
+				m.listSessions()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestManager_sessionHelpers(t *testing.T) {
+	m := NewManager(BrowserConfig{})
+	var closed atomic.Int32
+	for _, s := range []*BrowserSession{
+		{ID: "idle", LastUsed: time.Now().Add(-time.Hour)},
+		{ID: "fresh", LastUsed: time.Now()},
+		{ID: "leased", Active: true, LastUsed: time.Now().Add(-time.Hour)},
+	} {
+		s.ctx, s.cancel = context.Background(), func() { closed.Add(1) }
+		m.addSession(s)
+	}
+	if s, ok := m.getSession("fresh"); !ok || s.ID != "fresh" {
+		t.Errorf("getSession(fresh) = %v, %v", s, ok)
+	}
+	if _, ok := m.getSession("missing"); ok {
+		t.Errorf("getSession(missing) found a session")
+	}
+
+	// the reaper only removes sessions idle for long enough
+	for id, want := range map[string]bool{"idle": true, "fresh": false, "leased": false, "missing": false} {
+		if got := m.removeSession(id, time.Minute); got != want {
+			t.Errorf("removeSession(%s, time.Minute) = %v, want %v", id, got, want)
+		}
+	}
+	if got := len(m.listSessions()); got != 2 || closed.Load() != 1 {
+		t.Errorf("listSessions() = %d sessions with %d closed, want 2 left and 1 closed", got, closed.Load())
+	}
+
+	m.closeSessions()
+	if got := len(m.listSessions()); got != 0 || closed.Load() != 3 {
+		t.Errorf("closeSessions() left %d sessions and closed %d, want all closed", got, closed.Load())
+	}
+}
+
+func TestCanaryToken(t *testing.T) {
+	a := CanaryToken("<svg onload=alert(1)>")
+	if a != CanaryToken("<svg onload=alert(1)>") {
+		t.Errorf("CanaryToken() is not deterministic")
+	}
+	if a == CanaryToken("<img src=x onerror=alert(1)>") {
+		t.Errorf("CanaryToken() collides for different payloads")
+	}
+	if !strings.HasPrefix(a, "dalfox") || len(a) != len("dalfox")+12 {
+		t.Errorf("CanaryToken() = %q, unexpected format", a)
+	}
+}
+
+// chromePath returns a Chrome/Chromium binary or skips the test when none is installed
+func chromePath(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{"google-chrome", "chromium", "chromium-browser", "headless-shell"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p
+		}
+	}
+	t.Skip("Chrome/Chromium not found, skipping browser test")
+	return ""
+}
+
+// newTestManager returns an initialized manager that writes snapshots to a temp dir
+func newTestManager(t *testing.T, cfg BrowserConfig) *Manager {
+	t.Helper()
+	cfg.HeadlessMode = true
+	cfg.DisableSandbox = true
+	cfg.ChromiumBinaryPath = chromePath(t)
+	if cfg.SnapshotDir == "" {
+		cfg.SnapshotDir = t.TempDir()
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10
+	}
+	if cfg.WaitForAlertOnlyTime == 0 {
+		cfg.WaitForAlertOnlyTime = 2
+	}
+	t.Chdir(t.TempDir())
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { m.Shutdown() })
+	return m
+}
+
+func TestManager_ValidatePayloadHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/echo", http.StatusFound)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<script>alert(%q)</script>", r.Header.Get("X-Dalfox-Test"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{Headers: map[string]string{"X-Dalfox-Test": "header-ok"}})
+	res := m.ValidatePayload("", srv.URL+"/redirect", "header-test", "html")
+	if res.Error != nil {
+		t.Fatalf("ValidatePayload() error = %v", res.Error)
+	}
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect execution")
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "header-ok" {
+		t.Errorf("header after redirect = %q, want %q", got, "header-ok")
+	}
+	if res.Engine != EngineChromium {
+		t.Errorf("Engine = %q, want %q", res.Engine, EngineChromium)
+	}
+}
+
+func TestManager_nextUserAgent(t *testing.T) {
+	if got := NewManager(BrowserConfig{}).nextUserAgent(); got != "" {
+		t.Errorf("nextUserAgent() without config = %q, want Chrome's own", got)
+	}
+	if got := NewManager(BrowserConfig{UserAgent: "fixed"}).nextUserAgent(); got != "fixed" {
+		t.Errorf("nextUserAgent() = %q, want %q", got, "fixed")
+	}
+	m := NewManager(BrowserConfig{UserAgent: "fixed", UserAgents: []string{"a", "b"}})
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, m.nextUserAgent())
+	}
+	if want := []string{"a", "b", "a", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nextUserAgent() rotation = %v, want %v", got, want)
+	}
+}
+
+func TestManager_ValidatePayloadUserAgent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<script>alert(%q + '|' + navigator.userAgent)</script>", r.UserAgent())
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{UserAgents: []string{"dalfox-a", "dalfox-b"}})
+	for _, want := range []string{"dalfox-a", "dalfox-b"} {
+		res := m.ValidatePayload("", srv.URL, "ua-test", "html")
+		if res.Error != nil || len(res.ExecutionProofs) == 0 {
+			t.Fatalf("ValidatePayload() = %+v, want execution", res)
+		}
+		if got := res.ExecutionProofs[0].Evidence; got != want+"|"+want {
+			t.Errorf("request|navigator user agent = %q, want %q", got, want+"|"+want)
+		}
+	}
+}
+
+func Test_cookieParams(t *testing.T) {
+	cookies := []Cookie{
+		{Name: "sid", Value: "abc", Domain: "example.com", HTTPOnly: true},
+		{Name: "pref", Value: "1", Domain: ".example.com", Path: "/app"},
+		{Name: "host", Value: "x"},
+	}
+	got := cookieParams(cookies, "https://sub.example.com/page")
+	if len(got) != 3 {
+		t.Fatalf("cookieParams() returned %d params, want 3", len(got))
+	}
+	if got[0].Domain != ".example.com" || got[0].Path != "/" || !got[0].HTTPOnly {
+		t.Errorf("parent domain cookie = %+v", got[0])
+	}
+	if got[1].Domain != ".example.com" || got[1].Path != "/app" {
+		t.Errorf("dotted domain cookie = %+v", got[1])
+	}
+	if got[2].Domain != "" || got[2].URL != "https://sub.example.com/page" {
+		t.Errorf("host-only cookie = %+v", got[2])
+	}
+}
+
+func TestManager_ValidatePayloadBatch(t *testing.T) {
+	t.Run("not initialized", func(t *testing.T) {
+		m := NewManager(BrowserConfig{})
+		results := m.ValidatePayloadBatch("", []PayloadItem{{URL: "about:blank"}, {URL: "about:blank"}})
+		if len(results) != 2 {
+			t.Fatalf("ValidatePayloadBatch() returned %d results, want 2", len(results))
+		}
+		for i, r := range results {
+			if r == nil {
+				t.Errorf("result %d: missing", i)
+				continue
+			}
+			if !errors.Is(r.Error, ErrNotInitialized) {
+				t.Errorf("result %d: error = %v, want ErrNotInitialized", i, r.Error)
+			}
+		}
+	})
+
+	t.Run("no dialog leaks between items", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<script>alert("a");setTimeout(function(){alert("late")},300)</script>`)
+		})
+		mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<p>clean</p>`)
+		})
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+		results := m.ValidatePayloadBatch("", []PayloadItem{
+			{URL: srv.URL + "/a", Payload: "a", Context: "html"},
+			{URL: srv.URL + "/b", Payload: "b", Context: "html"},
+		})
+		if !results[0].ExecutionDetected {
+			t.Errorf("item 0: execution not detected")
+		}
+		if results[1].ExecutionDetected {
+			t.Errorf("item 1: dialog from item 0 leaked into result")
+		}
+	})
+
+	t.Run("total payload timeout per item", func(t *testing.T) {
+		m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 10, TotalPayloadTimeout: 1})
+		start := time.Now()
+		results := m.ValidatePayloadBatch("", []PayloadItem{
+			{URL: "data:text/html,<p>one</p>", Payload: "one", Context: "html"},
+			{URL: "data:text/html,<p>two</p>", Payload: "two", Context: "html"},
+		})
+		if elapsed := time.Since(start); elapsed > 8*time.Second {
+			t.Errorf("ValidatePayloadBatch() took %v, want each item cut at the total payload timeout", elapsed)
+		}
+		for i, r := range results {
+			if !errors.Is(r.Error, ErrPayloadTimeout) {
+				t.Errorf("item %d: error = %v, want ErrPayloadTimeout", i, r.Error)
+			}
+		}
+	})
+
+	t.Run("panicking item", func(t *testing.T) {
+		m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+		m.OnExecution(func(ExecutionProof) { panic("callback bug") })
+		results := m.ValidatePayloadBatch("", []PayloadItem{
+			{URL: "data:text/html,<script>alert(1)</script>", Payload: "panic-test", Context: "html"},
+			{URL: "data:text/html,<p>next</p>", Payload: "next", Context: "html"},
+		})
+		if !errors.Is(results[0].Error, ErrValidationPanic) {
+			t.Errorf("item 0: error = %v, want ErrValidationPanic", results[0].Error)
+		}
+		if results[1] == nil || results[1].ExecutionDetected {
+			t.Errorf("item 1: result = %+v, want a failed validation", results[1])
+		}
+	})
+}
+
+func TestManager_CapturePageHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<div id="sink">dalfox-marker</div><script>alert(1)</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{CapturePageHTML: true})
+	res := m.ValidatePayload("", srv.URL, "html-test", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+	}
+	proof := res.ExecutionProofs[0]
+	if !strings.Contains(proof.PageHTML, "dalfox-marker") {
+		t.Errorf("PageHTML = %q, want rendered DOM", proof.PageHTML)
+	}
+	if !filepath.IsAbs(proof.HTMLPath) {
+		t.Errorf("HTMLPath = %q, want an absolute path", proof.HTMLPath)
+	}
+}
+
+func TestManager_CaptureAnnotatedSVG(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>before</p><img src=x onerror=alert(1) width=50 height=50>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{CaptureAnnotatedSVG: true})
+	res := m.ValidatePayload("", srv.URL, "<img src=x onerror=alert(1)>", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+	}
+	path := res.ExecutionProofs[0].SVGPath
+	svg, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("annotated SVG %q not written: %v", path, err)
+	}
+	if !strings.Contains(string(svg), "<rect") || !strings.Contains(string(svg), "#1 img") {
+		t.Errorf("annotated SVG does not outline the injected element")
+	}
+
+	t.Run("screenshot selector", func(t *testing.T) {
+		page := `<body style="margin:0"><div id="target" style="position:absolute;left:30px;top:40px;width:120px;height:80px"></div><script>alert(1)</script></body>`
+		m := newTestManager(t, BrowserConfig{CaptureAnnotatedSVG: true, ScreenshotSelector: "#target", WaitForAlertOnlyTime: 1})
+		res := m.ValidatePayload("", "data:text/html,"+page, "selector-test", "html")
+		if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+			t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+		}
+		svg, err := os.ReadFile(res.ExecutionProofs[0].SVGPath)
+		if err != nil {
+			t.Fatalf("annotated SVG not written: %v", err)
+		}
+		want := `<rect x="30.0" y="40.0" width="120.0" height="80.0"`
+		if !strings.Contains(string(svg), want) || !strings.Contains(string(svg), "#1 div") {
+			t.Errorf("annotated SVG does not outline #target with %s", want)
+		}
+	})
+}
+
+func TestManager_OnExecution(t *testing.T) {
+	t.Run("callbacks in registration order", func(t *testing.T) {
+		m := NewManager(BrowserConfig{})
+		var calls []string
+		m.OnExecution(func(p ExecutionProof) { calls = append(calls, "a:"+p.Evidence) })
+		m.OnExecution(nil)
+		m.OnExecution(func(p ExecutionProof) { calls = append(calls, "b:"+p.Evidence) })
+		m.notifyExecution(ExecutionProof{Evidence: "1"})
+		if got := strings.Join(calls, ","); got != "a:1,b:1" {
+			t.Errorf("callbacks = %q, want %q", got, "a:1,b:1")
+		}
+	})
+
+	t.Run("called on dialog", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<script>alert("hooked")</script>`)
+		}))
+		defer srv.Close()
+
+		m := newTestManager(t, BrowserConfig{})
+		var got []ExecutionProof
+		var mu sync.Mutex
+		m.OnExecution(func(p ExecutionProof) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, p)
+		})
+		res := m.ValidatePayload("", srv.URL, "hook-test", "html")
+		if !res.ExecutionDetected {
+			t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(got) != 1 || got[0].Evidence != "hooked" {
+			t.Errorf("OnExecution callback got %+v, want one proof with evidence %q", got, "hooked")
+		}
+	})
+}
+
+func TestManager_CaptureStackTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>function fire(){ alert("traced") }</script><img src=x onerror=fire()>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{CaptureStackTrace: true})
+	res := m.ValidatePayload("", srv.URL, "<img src=x onerror=fire()>", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+	}
+	proof := res.ExecutionProofs[0]
+	if len(proof.StackTrace) == 0 || !strings.HasPrefix(proof.StackTrace[0], "fire (") {
+		t.Errorf("StackTrace = %q, want fire() as innermost frame", proof.StackTrace)
+	}
+	for _, msg := range proof.ConsoleLogs {
+		if strings.Contains(msg, traceMarker) {
+			t.Errorf("tracer output leaked into ConsoleLogs: %q", msg)
+		}
+	}
+}
+
+func TestManager_DryRunValidate(t *testing.T) {
+	withoutEncoders(t)
+	dir := t.TempDir()
+	m := NewManager(BrowserConfig{SnapshotDir: dir, ScreenshotFormat: "webp", CapturePageHTML: true})
+	res := m.DryRunValidate("http://t.test/?q=<svg>", "<svg>")
+	if res.Error != nil || res.ExecutionDetected || res.DryRun == nil {
+		t.Fatalf("DryRunValidate() = %+v, want a dry-run result without execution", res)
+	}
+	info := res.DryRun
+	if info.Canary != CanaryToken("<svg>") || len(info.TargetSHA256) != 64 || len(info.PayloadSHA256) != 64 {
+		t.Errorf("DryRun hashes = %+v", info)
+	}
+	if !strings.HasPrefix(info.SnapshotName, info.TargetSHA256[:12]+"_"+info.PayloadSHA256[:12]+"_") {
+		t.Errorf("SnapshotName = %q, want target and payload hash prefixes", info.SnapshotName)
+	}
+	// without cwebp the screenshot ends up as JPEG
+	if want := filepath.Join(dir, "jpg", info.SnapshotName+".jpg"); info.ScreenshotPath != want {
+		t.Errorf("ScreenshotPath = %q, want %q", info.ScreenshotPath, want)
+	}
+	if info.HTMLPath == "" || info.SVGPath != "" {
+		t.Errorf("HTMLPath = %q, SVGPath = %q, want only the enabled capture", info.HTMLPath, info.SVGPath)
+	}
+	if m.IsInitialized() {
+		t.Errorf("DryRunValidate() initialized the manager")
+	}
+
+	if res := m.DryRunValidate("http://t.test/%zz", "x"); res.Error == nil {
+		t.Errorf("DryRunValidate() with an invalid URL should fail")
+	}
+}
+
+func TestManager_ValidatePayloadCtx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>no dialog here</p>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 10})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	res := m.ValidatePayloadCtx(ctx, "", srv.URL, "ctx-test", "html")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ValidatePayloadCtx() took %v, want it to stop at the context deadline", elapsed)
+	}
+	if !errors.Is(res.Error, context.DeadlineExceeded) {
+		t.Errorf("ValidatePayloadCtx() error = %v, want context.DeadlineExceeded", res.Error)
+	}
+}
+
+func TestManager_ValidatePayloadCtxCancel(t *testing.T) {
+	loaded := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>no dialog here</p>`)
+		select {
+		case loaded <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 10})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// cancel while the validation waits for a dialog
+		<-loaded
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	res := m.ValidatePayloadCtx(ctx, "", srv.URL, "cancel-test", "html")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ValidatePayloadCtx() took %v, want it to stop when cancelled", elapsed)
+	}
+	if !errors.Is(res.Error, context.Canceled) {
+		t.Errorf("ValidatePayloadCtx() error = %v, want context.Canceled", res.Error)
+	}
+	if n := len(m.tabs); n != 0 {
+		t.Errorf("%d tabs still held after the cancelled validation", n)
+	}
+}
+
+func TestManager_TotalPayloadTimeout(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 10, TotalPayloadTimeout: 1})
+	start := time.Now()
+	res := m.ValidatePayload("", "data:text/html,<p>no execution</p>", "budget-test", "html")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ValidatePayload() took %v, want it cut at the total payload timeout", elapsed)
+	}
+	if !errors.Is(res.Error, ErrPayloadTimeout) || !errors.Is(res.Error, context.DeadlineExceeded) {
+		t.Errorf("ValidatePayload() error = %v, want ErrPayloadTimeout", res.Error)
+	}
+}
+
+func TestManager_ValidatePayloadFinalURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/results?"+r.URL.RawQuery, http.StatusFound)
+	})
+	mux.HandleFunc("/results", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<p>%s</p>", r.URL.Query().Get("q"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{})
+	target := srv.URL + "/search?q=%3Cscript%3Ealert(1)%3C/script%3E"
+	res := m.ValidatePayload("", target, "<script>alert(1)</script>", "html")
+	if !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	proof := res.ExecutionProofs[0]
+	if proof.PageURL != target || !strings.HasPrefix(proof.FinalURL, srv.URL+"/results?") {
+		t.Errorf("PageURL, FinalURL = %q, %q, want the input and the redirect target", proof.PageURL, proof.FinalURL)
+	}
+}
+
+func TestManager_ValidatePayloadIframe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/frame", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<script>alert('cross-site frame')</script>")
+	})
+	mux.HandleFunc("/cross-site", func(w http.ResponseWriter, r *http.Request) {
+		// the frame is the same server under another site, an out-of-process
+		// frame under site isolation
+		port := r.Host[strings.LastIndex(r.Host, ":"):]
+		fmt.Fprintf(w, `<iframe src="http://localhost%s/frame"></iframe>`, port)
+	})
+	mux.HandleFunc("/srcdoc", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<iframe srcdoc="<script>alert('srcdoc')</script>"></iframe>`)
+	})
+	mux.HandleFunc("/top", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<script>alert('top')</script>")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	m := newTestManager(t, BrowserConfig{})
+	tests := []struct {
+		path         string
+		wantEvidence string
+		wantFrameURL string
+	}{
+		{"/srcdoc", "srcdoc", "about:srcdoc"},
+		{"/cross-site", "cross-site frame", "http://localhost" + port + "/frame"},
+		{"/top", "top", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res := m.ValidatePayload("", srv.URL+tt.path, "iframe-test", "html")
+			if res.Error != nil || len(res.ExecutionProofs) == 0 {
+				t.Fatalf("ValidatePayload() = %+v, want execution", res)
+			}
+			proof := res.ExecutionProofs[0]
+			if proof.Evidence != tt.wantEvidence || proof.FrameURL != tt.wantFrameURL {
+				t.Errorf("Evidence, FrameURL = %q, %q, want %q, %q", proof.Evidence, proof.FrameURL, tt.wantEvidence, tt.wantFrameURL)
+			}
+		})
+	}
+}
+
+func TestManager_gateTasks(t *testing.T) {
+	if got := NewManager(BrowserConfig{}).gateTasks(); len(got) != 0 {
+		t.Errorf("gateTasks() without selectors = %d actions, want none", len(got))
+	}
+	m := NewManager(BrowserConfig{ClickSelectors: []string{"#login", "#tab"}, WaitForSelector: "#panel"})
+	if got := m.gateTasks(); len(got) != 3 {
+		t.Errorf("gateTasks() = %d actions, want 2 clicks and a wait", len(got))
+	}
+}
+
+func TestManager_ValidatePayloadClickAndWaitForSelector(t *testing.T) {
+	page := "data:text/html," + url.PathEscape(`<button id="tab" onclick="setTimeout(function(){
+		var d = document.createElement('div'); d.id = 'panel'; d.textContent = 'panel';
+		document.body.appendChild(d); alert('panel');
+	}, 100)">tab</button>`)
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1, ClickSelectors: []string{"#tab"}, WaitForSelector: "#panel"})
+	if res := m.ValidatePayload("", page, "gate-test", "html"); !res.ExecutionDetected {
+		t.Errorf("ValidatePayload() with the click = %+v, want execution", res)
+	}
+
+	m = newTestManager(t, BrowserConfig{Timeout: 1, WaitForAlertOnlyTime: 1, WaitForSelector: "#panel"})
+	res := m.ValidatePayload("", page, "gate-test", "html")
+	if res.ExecutionDetected || res.Error == nil || !strings.Contains(res.Error.Error(), "#panel") {
+		t.Errorf("ValidatePayload() without the click = %+v, want a wait for selector error", res)
+	}
+}
+
+func Test_isLocalDocument(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"data:text/html,<script>alert(1)</script>", true},
+		{"DATA:text/html;base64,PHNjcmlwdD4=", true},
+		{"about:blank", true},
+		{"http://t.test/?q=data:x", false},
+		{"https://t.test/", false},
+		{"t.test/about:", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalDocument(tt.url); got != tt.want {
+			t.Errorf("isLocalDocument(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestManager_ValidatePayloadDataURL(t *testing.T) {
+	// URL-scoped cookies cannot be set for a data: document and must not break it
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1, Cookies: []Cookie{{Name: "sid", Value: "1"}}})
+	res := m.ValidatePayload("", "data:text/html,<img src=x onerror=alert('offline')>", "<img src=x onerror=alert('offline')>", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() missed the dialog of a data: URL: %v", res.Error)
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "offline" {
+		t.Errorf("Evidence = %q, want %q", got, "offline")
+	}
+
+	res = m.ValidatePayloadPOST("", "data:text/html,x", "q=1", "", "x", "html")
+	if res.Error == nil {
+		t.Errorf("ValidatePayloadPOST() to a data: URL should fail")
+	}
+}
+
+func TestManager_snapshotBaseName(t *testing.T) {
+	m := NewManager(BrowserConfig{})
+	seen := make(map[string]bool)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := m.snapshotBaseName("http://t.test/", "<svg>")
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[name] {
+				t.Errorf("snapshotBaseName() returned %q twice", name)
+			}
+			seen[name] = true
+		}()
+	}
+	wg.Wait()
+}
+
+func TestManager_ConcurrentEvidenceFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>alert(1)</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{CapturePageHTML: true})
+	results := make([]*ValidationResult, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.ValidatePayload("", srv.URL, "same-payload", "html")
+		}()
+	}
+	wg.Wait()
+
+	var paths []string
+	for i, res := range results {
+		if !res.ExecutionDetected {
+			t.Fatalf("validation %d did not detect execution: %v", i, res.Error)
+		}
+		path := res.ExecutionProofs[0].HTMLPath
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("validation %d evidence file missing: %v", i, err)
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] == paths[1] {
+		t.Errorf("concurrent validations wrote the same evidence file %q", paths[0])
+	}
+}
+
+func TestManager_PromptDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>var answer = prompt('name?', '<svg onload=prompt(1)>'); location.hash = answer;</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{})
+	res := m.ValidatePayload("", srv.URL, "<svg onload=prompt(1)>", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect the prompt: %v", res.Error)
+	}
+	proof := res.ExecutionProofs[0]
+	if proof.ExecutionType != "prompt" || proof.PromptDefault != "<svg onload=prompt(1)>" {
+		t.Errorf("proof = %q with default %q, want prompt with the echoed payload", proof.ExecutionType, proof.PromptDefault)
+	}
+}
+
+func TestManager_VerifyStoredXSS(t *testing.T) {
+	var mu sync.Mutex
+	var comments []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/comment", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		comments = append(comments, r.URL.Query().Get("c"))
+		mu.Unlock()
+		fmt.Fprint(w, `<script>alert('submit page')</script>saved`)
+	})
+	mux.HandleFunc("/thread", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, strings.Join(comments, "<br>"))
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<p>nothing stored here</p>")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+	payload := "<script>alert('stored')</script>"
+	inject := srv.URL + "/comment?c=" + url.QueryEscape(payload)
+	res := m.VerifyStoredXSS(inject, srv.URL+"/thread", payload, "")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("VerifyStoredXSS() did not detect execution on the view page: %v", res.Error)
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "stored" {
+		t.Errorf("Evidence = %q, want the view page dialog only", got)
+	}
+	if got, want := res.ExecutionProofs[0].PayloadSHA256, fmt.Sprintf("%x", sha256.Sum256([]byte(payload))); got != want {
+		t.Errorf("PayloadSHA256 = %s, want the hash of the stored payload %s", got, want)
+	}
+
+	if res := m.VerifyStoredXSS(inject, srv.URL+"/empty", payload, ""); res.ExecutionDetected {
+		t.Errorf("VerifyStoredXSS() counted a dialog of the inject page")
+	}
+}
+
+func TestManager_ScreenshotToDiskDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>memory</p><script>alert(1)</script>`)
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	m := newTestManager(t, BrowserConfig{SnapshotDir: dir, ScreenshotToDiskDisabled: true, CapturePageHTML: true, CaptureAnnotatedSVG: true})
+	res := m.ValidatePayload("", srv.URL, "memory-test", "html")
+	if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() did not detect execution: %v", res.Error)
+	}
+	proof := res.ExecutionProofs[0]
+	if len(proof.ScreenshotData) == 0 || !strings.Contains(proof.PageHTML, "memory") {
+		t.Errorf("in-memory evidence missing: %d screenshot bytes, PageHTML %q", len(proof.ScreenshotData), proof.PageHTML)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(proof.ScreenshotData)); err != nil {
+		t.Errorf("ScreenshotData is not a raw image: %v", err)
+	}
+	if proof.ScreenshotPath != "" || proof.HTMLPath != "" || proof.SVGPath != "" {
+		t.Errorf("evidence paths set without disk access: %q %q %q", proof.ScreenshotPath, proof.HTMLPath, proof.SVGPath)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("snapshot dir was created: %v", err)
+	}
+}
+
+func TestManager_PageTitleWithoutExecution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<title>Blocked by WAF</title><p>nope</p>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+	res := m.ValidatePayload("", srv.URL, "title-test", "html")
+	if res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() unexpectedly detected execution")
+	}
+	if res.PageTitle != "Blocked by WAF" {
+		t.Errorf("PageTitle = %q, want %q", res.PageTitle, "Blocked by WAF")
+	}
+}
+
+func Test_truncateEvidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{name: "short", s: "1", limit: 20, want: "1"},
+		{name: "exact", s: "0123456789", limit: 10, want: "0123456789"},
+		{name: "cut", s: strings.Repeat("a", 30), limit: 20, want: "aaaaaa" + evidenceTruncatedMarker},
+		{name: "rune boundary", s: "aaaaa" + strings.Repeat("é", 10), limit: 20, want: "aaaaa" + evidenceTruncatedMarker},
+		{name: "no limit", s: strings.Repeat("a", 30), limit: -1, want: strings.Repeat("a", 30)},
+		{name: "limit below marker", s: strings.Repeat("a", 30), limit: 3, want: "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateEvidence(tt.s, tt.limit)
+			if got != tt.want {
+				t.Errorf("truncateEvidence() = %q, want %q", got, tt.want)
+			}
+			if tt.limit >= 0 && len(got) > tt.limit {
+				t.Errorf("truncateEvidence() = %d bytes, over the limit of %d", len(got), tt.limit)
+			}
+		})
+	}
+
+	for cfg, want := range map[int]int{0: defaultMaxEvidenceLen, 100: 100, -1: -1, -5: -1} {
+		if got := NewManager(BrowserConfig{MaxEvidenceLen: cfg}).maxEvidenceLen(); got != want {
+			t.Errorf("maxEvidenceLen() with MaxEvidenceLen %d = %d, want %d", cfg, got, want)
+		}
+	}
+}
+
+func TestManager_ValidatePayloadLongEvidence(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{MaxEvidenceLen: 64})
+	res := m.ValidatePayload("", "data:text/html,<script>alert('x'.repeat(100000))</script>", "long-test", "html")
+	if res.Error != nil || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	if got := res.ExecutionProofs[0].Evidence; len(got) != 64 || !strings.HasSuffix(got, evidenceTruncatedMarker) {
+		t.Errorf("Evidence = %q, want it cut to 64 bytes", got)
+	}
+}
+
+func Test_dialogEvidence(t *testing.T) {
+	tests := []struct {
+		name string
+		dlg  *page.EventJavascriptDialogOpening
+		want string
+	}{
+		{name: "alert", dlg: &page.EventJavascriptDialogOpening{Type: page.DialogTypeAlert, Message: "1"}, want: "1"},
+		{name: "prompt without default", dlg: &page.EventJavascriptDialogOpening{Type: page.DialogTypePrompt, Message: "q"}, want: "q"},
+		{name: "prompt with default", dlg: &page.EventJavascriptDialogOpening{Type: page.DialogTypePrompt, Message: "q", DefaultPrompt: "<x>"}, want: "q (default: <x>)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dialogEvidence(tt.dlg); got != tt.want {
+				t.Errorf("dialogEvidence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_WaitForNetworkIdle(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<div id=sink></div><script>fetch("/slow").then(r=>r.text()).then(t=>{document.getElementById("sink").innerHTML=t})</script>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		fmt.Fprint(w, `<img src=x onerror=alert("late")>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1, WaitForNetworkIdle: true})
+	res := m.ValidatePayload("", srv.URL, "late-test", "html")
+	if !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() missed the dialog raised after a deferred fetch: %v", res.Error)
+	}
+}
+
+func TestManager_PostNavigateWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fires after the 1s dialog window would have closed without the settle time
+		fmt.Fprint(w, `<script>setTimeout(function(){alert("hydrated")},1500)</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1, PostNavigateWaitMs: 1000})
+	res := m.ValidatePayload("", srv.URL, "hydrate-test", "html")
+	if !res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() missed the dialog raised after hydration: %v", res.Error)
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "hydrated" {
+		t.Errorf("Evidence = %q, want %q", got, "hydrated")
+	}
+}
+
+func TestManager_ValidatePayloadWithTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>setTimeout(function(){alert("slow")},1500)</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+	if res := m.ValidatePayload("", srv.URL, "slow-test", "html"); res.ExecutionDetected {
+		t.Errorf("ValidatePayload() detected a dialog raised after the configured window")
+	}
+	if res := m.ValidatePayloadWithTimeout("", srv.URL, "slow-test", "html", 3); !res.ExecutionDetected {
+		t.Errorf("ValidatePayloadWithTimeout() missed the late dialog: %v", res.Error)
+	}
+}
+
+func TestManager_ValidateURLs(t *testing.T) {
+	t.Run("not initialized", func(t *testing.T) {
+		results := NewManager(BrowserConfig{}).ValidateURLs([]string{"about:blank", "about:blank"}, "", "", 2)
+		for i, r := range results {
+			if r == nil || !errors.Is(r.Error, ErrNotInitialized) {
+				t.Errorf("result %d = %+v, want ErrNotInitialized", i, r)
+			}
+		}
+	})
+
+	t.Run("results keep url order", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/xss", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "<script>alert(%q)</script>", r.URL.Query().Get("n"))
+		})
+		mux.HandleFunc("/safe", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<p>safe</p>")
+		})
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+		urls := []string{srv.URL + "/xss?n=0", srv.URL + "/safe", srv.URL + "/xss?n=2"}
+		results := m.ValidateURLs(urls, "[headless-check]", string(ContextHeadless), 3)
+		if len(results) != len(urls) {
+			t.Fatalf("ValidateURLs() returned %d results, want %d", len(results), len(urls))
+		}
+		for i, want := range []bool{true, false, true} {
+			if results[i].ExecutionDetected != want {
+				t.Errorf("result %d ExecutionDetected = %v, want %v", i, results[i].ExecutionDetected, want)
+			}
+		}
+		if results[2].ExecutionDetected {
+			if got := results[2].ExecutionProofs[0].Evidence; got != "2" {
+				t.Errorf("result 2 evidence = %q, want %q", got, "2")
+			}
+			if got := results[2].ExecutionProofs[0].ExecutionContext; got != string(ContextHeadless) {
+				t.Errorf("result 2 context = %q, want %q", got, ContextHeadless)
+			}
+		}
+	})
+
+	t.Run("results as each url finishes", func(t *testing.T) {
+		release := make(chan struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			fmt.Fprint(w, "<p>slow</p>")
+		})
+		mux.HandleFunc("/xss", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<script>alert("fast")</script>`)
+		})
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+		var once sync.Once
+		unblock := func() { once.Do(func() { close(release) }) }
+		defer unblock()
+
+		m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+		urls := []string{srv.URL + "/slow", srv.URL + "/xss"}
+		var order []int
+		m.ValidateURLsFunc(urls, "[headless-check]", string(ContextHeadless), 2, func(i int, result *ValidationResult) {
+			order = append(order, i)
+			if i == 1 {
+				// the slow url is only answered once the fast one was reported
+				unblock()
+			}
+		})
+		if !reflect.DeepEqual(order, []int{1, 0}) {
+			t.Errorf("ValidateURLsFunc() reported urls in order %v, want [1 0]", order)
+		}
+	})
+}
+
+func TestManager_NavRetries(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// drop the first connection to simulate a transient failure
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		fmt.Fprint(w, `<script>alert("retried")</script>`)
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{NavRetries: 2, NavRetryBackoffMs: 50})
+	res := m.ValidatePayload("", srv.URL, "retry-test", "html")
+	if res.Error != nil {
+		t.Fatalf("ValidatePayload() error = %v", res.Error)
+	}
+	if res.NavRetries != 1 {
+		t.Errorf("NavRetries = %d, want 1", res.NavRetries)
+	}
+	if !res.ExecutionDetected {
+		t.Errorf("ValidatePayload() did not detect execution after the retry")
+	}
+
+	res = m.ValidatePayload("", "http://dalfox-does-not-exist.invalid/", "retry-test", "html")
+	if res.Error == nil || res.NavRetries != 0 {
+		t.Errorf("unresolvable host: error = %v, NavRetries = %d, want an error without retries", res.Error, res.NavRetries)
+	}
+}
@@ -0,0 +1,124 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// durationBuckets are the upper bounds in seconds of the validation duration
+// histogram, spanning data: URLs to navigations hitting the default timeout
+var durationBuckets = [...]float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60}
+
+// netErrorCode extracts the Chrome net error of a failed navigation
+var netErrorCode = regexp.MustCompile(`net::(ERR_[A-Z0-9_]+)`)
+
+// navErrorType labels a navigation error for the navigation error counter:
+// "timeout", "canceled", the Chrome net error such as "err_connection_refused",
+// or "other"
+func navErrorType(err error) string {
+	switch {
+	case errors.Is(err, ErrNavigationTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	if m := netErrorCode.FindStringSubmatch(err.Error()); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return "other"
+}
+
+// observeDuration adds d to the validation duration histogram
+func (s *validationStats) observeDuration(d time.Duration) {
+	i := sort.SearchFloat64s(durationBuckets[:], d.Seconds())
+	s.durationBuckets[i].Add(1) // the last slot is +Inf
+}
+
+// navFailure counts a navigation that failed with err
+func (s *validationStats) navFailure(err error) {
+	s.navFailures.Add(1)
+	s.navErrorsMu.Lock()
+	defer s.navErrorsMu.Unlock()
+	if s.navErrors == nil {
+		s.navErrors = make(map[string]uint64)
+	}
+	s.navErrors[navErrorType(err)]++
+}
+
+// Collectors returns Prometheus collectors reading the validation counters of
+// the manager, for a service embedding the scanner to register with its own
+// registry:
+//
+//	dalfox_browser_validations_total
+//	dalfox_browser_executions_total
+//	dalfox_browser_navigation_errors_total{type="timeout"}
+//	dalfox_browser_screenshots_written_total
+//	dalfox_browser_validation_duration_seconds (histogram)
+//
+// The collectors read the counters on every scrape, so they cost nothing
+// between scrapes. Register them with prometheus.WrapRegistererWith to add
+// constant labels, e.g. one per manager.
+func (m *Manager) Collectors() []prometheus.Collector {
+	s := &m.stats
+	return []prometheus.Collector{
+		statsCollector{
+			desc: prometheus.NewDesc("dalfox_browser_validations_total", "Validations that reached a browser tab.", nil, nil),
+			collect: func(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(s.validations.Load()))
+			},
+		},
+		statsCollector{
+			desc: prometheus.NewDesc("dalfox_browser_executions_total", "Validations that confirmed payload execution.", nil, nil),
+			collect: func(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(s.executions.Load()))
+			},
+		},
+		statsCollector{
+			desc: prometheus.NewDesc("dalfox_browser_navigation_errors_total", "Validations whose page did not load, by error type.", []string{"type"}, nil),
+			collect: func(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+				s.navErrorsMu.Lock()
+				defer s.navErrorsMu.Unlock()
+				for t, n := range s.navErrors {
+					ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(n), t)
+				}
+			},
+		},
+		statsCollector{
+			desc: prometheus.NewDesc("dalfox_browser_screenshots_written_total", "Screenshot files written.", nil, nil),
+			collect: func(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(s.screenshots.Load()))
+			},
+		},
+		statsCollector{
+			desc: prometheus.NewDesc("dalfox_browser_validation_duration_seconds", "Duration of validations.", nil, nil),
+			collect: func(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+				buckets := make(map[float64]uint64, len(durationBuckets))
+				var cumulative uint64
+				for i, bound := range durationBuckets {
+					cumulative += s.durationBuckets[i].Load()
+					buckets[bound] = cumulative
+				}
+				cumulative += s.durationBuckets[len(durationBuckets)].Load()
+				sum := time.Duration(s.totalDuration.Load()).Seconds()
+				ch <- prometheus.MustNewConstHistogram(desc, cumulative, sum, buckets)
+			},
+		},
+	}
+}
+
+// statsCollector exposes one metric family computed from validationStats at
+// collection time
+type statsCollector struct {
+	desc    *prometheus.Desc
+	collect func(desc *prometheus.Desc, ch chan<- prometheus.Metric)
+}
+
+func (c statsCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.desc }
+
+func (c statsCollector) Collect(ch chan<- prometheus.Metric) { c.collect(c.desc, ch) }
@@ -0,0 +1,82 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_navErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{navigationError(context.DeadlineExceeded), "timeout"},
+		{context.Canceled, "canceled"},
+		{errors.New("page load error net::ERR_CONNECTION_REFUSED"), "err_connection_refused"},
+		{fmt.Errorf("navigate: %w", errors.New("net::ERR_NAME_NOT_RESOLVED")), "err_name_not_resolved"},
+		{errors.New("websocket closed"), "other"},
+	}
+	for _, tt := range tests {
+		if got := navErrorType(tt.err); got != tt.want {
+			t.Errorf("navErrorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestManager_Collectors(t *testing.T) {
+	m := NewManager(BrowserConfig{})
+	m.stats.record(&ValidationResult{ExecutionDetected: true, ValidationDuration: 300 * time.Millisecond})
+	m.stats.record(&ValidationResult{ValidationDuration: 90 * time.Second})
+	m.stats.navFailure(errors.New("net::ERR_CONNECTION_REFUSED"))
+	m.stats.navFailure(errors.New("net::ERR_CONNECTION_REFUSED"))
+	m.stats.navFailure(navigationError(context.DeadlineExceeded))
+
+	reg := prometheus.NewRegistry()
+	// an embedder may label the metrics of each manager
+	wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"scanner": "a"}, reg)
+	for _, c := range m.Collectors() {
+		if err := wrapped.Register(c); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	want := `
+# HELP dalfox_browser_executions_total Validations that confirmed payload execution.
+# TYPE dalfox_browser_executions_total counter
+dalfox_browser_executions_total{scanner="a"} 1
+# HELP dalfox_browser_navigation_errors_total Validations whose page did not load, by error type.
+# TYPE dalfox_browser_navigation_errors_total counter
+dalfox_browser_navigation_errors_total{scanner="a",type="err_connection_refused"} 2
+dalfox_browser_navigation_errors_total{scanner="a",type="timeout"} 1
+# HELP dalfox_browser_screenshots_written_total Screenshot files written.
+# TYPE dalfox_browser_screenshots_written_total counter
+dalfox_browser_screenshots_written_total{scanner="a"} 0
+# HELP dalfox_browser_validation_duration_seconds Duration of validations.
+# TYPE dalfox_browser_validation_duration_seconds histogram
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="0.25"} 0
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="0.5"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="1"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="2"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="5"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="10"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="20"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="30"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="60"} 1
+dalfox_browser_validation_duration_seconds_bucket{scanner="a",le="+Inf"} 2
+dalfox_browser_validation_duration_seconds_sum{scanner="a"} 90.3
+dalfox_browser_validation_duration_seconds_count{scanner="a"} 2
+# HELP dalfox_browser_validations_total Validations that reached a browser tab.
+# TYPE dalfox_browser_validations_total counter
+dalfox_browser_validations_total{scanner="a"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,97 @@
+package browser
+
+import (
+	"errors"
+	neturl "net/url"
+	"strings"
+	"unicode"
+)
+
+// maxMinimizeTrials bounds the validations a single Minimize call runs
+const maxMinimizeTrials = 64
+
+// Minimize looks for the shortest variant of payload that still executes when
+// it replaces payload in url, for cleaner PoCs. payload may appear in url as is
+// or query or path escaped; candidates are substituted the same way. Tokens of
+// the payload (see payloadTokens) are removed in halving chunks, keeping every
+// removal after which ValidatePayload still detects execution; at most
+// maxMinimizeTrials such validations run, each waiting the full dialog window
+// when it fails.
+//
+// It returns the shortest executing payload and its result. When the original
+// payload does not execute, or cannot be found in url, payload is returned
+// unchanged with the result explaining why.
+func (m *Manager) Minimize(url, payload, contextStr string) (string, *ValidationResult) {
+	result := m.ValidatePayload("", url, payload, contextStr)
+	if !result.ExecutionDetected {
+		return payload, result
+	}
+	escape := payloadEscaper(url, payload)
+	if escape == nil {
+		return payload, &ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: errors.New("payload not found in url")}
+	}
+
+	tokens := payloadTokens(payload)
+	trials := 0
+	for size := len(tokens) / 2; size >= 1 && trials < maxMinimizeTrials; size /= 2 {
+		for i := 0; i+size <= len(tokens) && trials < maxMinimizeTrials; {
+			candidate := strings.Join(tokens[:i], "") + strings.Join(tokens[i+size:], "")
+			trials++
+			if candidate != "" {
+				trial := m.ValidatePayload("", strings.ReplaceAll(url, escape(payload), escape(candidate)), candidate, contextStr)
+				if trial.ExecutionDetected {
+					tokens = append(tokens[:i:i], tokens[i+size:]...)
+					result = trial
+					continue
+				}
+			}
+			i += size
+		}
+	}
+	return strings.Join(tokens, ""), result
+}
+
+// payloadEscaper returns the encoding payload appears in url with, or nil when
+// it does not appear
+func payloadEscaper(url, payload string) func(string) string {
+	for _, escape := range []func(string) string{
+		func(s string) string { return s },
+		neturl.QueryEscape,
+		neturl.PathEscape,
+	} {
+		if strings.Contains(url, escape(payload)) {
+			return escape
+		}
+	}
+	return nil
+}
+
+// payloadTokens splits payload into the units Minimize removes: runs of letters
+// and digits, runs of whitespace and single other characters, so that names such
+// as "onload" or "alert" are dropped whole.
+func payloadTokens(payload string) []string {
+	var tokens []string
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		default:
+			return 0
+		}
+	}
+	start, prev := 0, -1
+	for i, r := range payload {
+		c := class(r)
+		if i > 0 && (c == 0 || c != prev) {
+			tokens = append(tokens, payload[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(payload) {
+		tokens = append(tokens, payload[start:])
+	}
+	return tokens
+}
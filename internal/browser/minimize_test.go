@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_payloadTokens(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    []string
+	}{
+		{"", nil},
+		{"<svg onload=alert(1)>", []string{"<", "svg", " ", "onload", "=", "alert", "(", "1", ")", ">"}},
+		{"a  b", []string{"a", "  ", "b"}},
+		{"\"'", []string{"\"", "'"}},
+		{"écrire(1)", []string{"écrire", "(", "1", ")"}},
+	}
+	for _, tt := range tests {
+		if got := payloadTokens(tt.payload); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("payloadTokens(%q) = %q, want %q", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func Test_payloadEscaper(t *testing.T) {
+	payload := "<svg onload=alert(1)>"
+	tests := []struct {
+		name string
+		url  string
+		want string // escaped payload, "" when not found
+	}{
+		{name: "raw", url: "data:text/html," + payload, want: payload},
+		{name: "query", url: "http://t.test/?q=" + url.QueryEscape(payload), want: url.QueryEscape(payload)},
+		{name: "path", url: "http://t.test/" + url.PathEscape(payload), want: url.PathEscape(payload)},
+		{name: "missing", url: "http://t.test/?q=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if escape := payloadEscaper(tt.url, payload); escape != nil {
+				got = escape(payload)
+			}
+			if got != tt.want {
+				t.Errorf("payloadEscaper() escapes to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Minimize(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{WaitForAlertOnlyTime: 1})
+	payload := `<img src=x title="padding" onerror=alert(1) class=noise>`
+	got, res := m.Minimize("data:text/html,"+url.PathEscape(payload), payload, "html")
+	if !res.ExecutionDetected {
+		t.Fatalf("Minimize() result = %+v, want the minimized payload to execute", res)
+	}
+	if len(got) >= len(payload) || !strings.Contains(got, "alert") || strings.Contains(got, "padding") {
+		t.Errorf("Minimize() = %q, want a shorter payload without the padding", got)
+	}
+
+	inert := "<b>inert</b>"
+	if got, res := m.Minimize("data:text/html,"+inert, inert, "html"); got != inert || res.ExecutionDetected {
+		t.Errorf("Minimize() of a payload that does not execute = %q, %+v", got, res)
+	}
+}
@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// networkIdleWindow is how long no request may be in flight for the page to
+// count as network idle
+const networkIdleWindow = 500 * time.Millisecond
+
+// networkTracker counts the requests of a tab that are still in flight
+type networkTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]struct{}
+	last     time.Time // last time a request started or ended
+}
+
+// handle updates the tracker from Network domain events and reports whether ev
+// was one of them.
+func (t *networkTracker) handle(ev interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if t.inflight == nil {
+			t.inflight = make(map[network.RequestID]struct{})
+		}
+		// redirects reuse the request ID, so the set keeps them counted once
+		t.inflight[e.RequestID] = struct{}{}
+	case *network.EventLoadingFinished:
+		delete(t.inflight, e.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inflight, e.RequestID)
+	default:
+		return false
+	}
+	t.last = time.Now()
+	return true
+}
+
+// idleIn returns how long it takes at least until the network can be idle for
+// window, or 0 when it already is.
+func (t *networkTracker) idleIn(window time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.inflight) > 0 {
+		return window
+	}
+	if quiet := time.Since(t.last); quiet < window {
+		return window - quiet
+	}
+	return 0
+}
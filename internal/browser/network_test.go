@@ -0,0 +1,37 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestNetworkTracker_idleIn(t *testing.T) {
+	var tr networkTracker
+	if got := tr.idleIn(networkIdleWindow); got != 0 {
+		t.Errorf("idleIn() without traffic = %v, want 0", got)
+	}
+
+	tr.handle(&network.EventRequestWillBeSent{RequestID: "1"})
+	tr.handle(&network.EventRequestWillBeSent{RequestID: "1"}) // redirect
+	tr.handle(&network.EventRequestWillBeSent{RequestID: "2"})
+	if got := tr.idleIn(networkIdleWindow); got != networkIdleWindow {
+		t.Errorf("idleIn() with requests in flight = %v, want %v", got, networkIdleWindow)
+	}
+
+	tr.handle(&network.EventLoadingFinished{RequestID: "1"})
+	tr.handle(&network.EventLoadingFailed{RequestID: "2"})
+	if got := tr.idleIn(networkIdleWindow); got <= 0 || got > networkIdleWindow {
+		t.Errorf("idleIn() right after the last request = %v, want within (0, %v]", got, networkIdleWindow)
+	}
+
+	tr.last = time.Now().Add(-networkIdleWindow)
+	if got := tr.idleIn(networkIdleWindow); got != 0 {
+		t.Errorf("idleIn() after a quiet window = %v, want 0", got)
+	}
+
+	if tr.handle(&network.EventDataReceived{RequestID: "3"}) {
+		t.Errorf("handle() claimed an unrelated event")
+	}
+}
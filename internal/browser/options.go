@@ -0,0 +1,58 @@
+package browser
+
+import (
+	"strings"
+
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+// defaultHeadlessTimeout is used when model.Options.HeadlessTimeout is not set
+const defaultHeadlessTimeout = 30
+
+// ConfigFromOptions builds the browser configuration for a scan from its CLI
+// options. The dialog wait is a sixth of the headless timeout, matching the
+// Puppeteer verifier. Custom headers, user agent and cookie are sent with every
+// validation request and traffic goes through the scan proxy.
+func ConfigFromOptions(opts model.Options) BrowserConfig {
+	timeout := opts.HeadlessTimeout
+	if timeout <= 0 {
+		timeout = defaultHeadlessTimeout
+	}
+	wait := timeout / 6
+	if wait < 1 {
+		wait = 1
+	}
+
+	cfg := BrowserConfig{
+		HeadlessMode:         true,
+		DisableSandbox:       opts.DisableSandbox,
+		Timeout:              timeout,
+		WaitForAlertOnlyTime: wait,
+		ChromiumBinaryPath:   opts.ChromiumPath,
+		TakeScreenshots:      true,
+		ScreenshotQuality:    opts.ScreenshotQuality,
+		MaxTabs:              opts.HeadlessConcurrency,
+		ProxyServer:          opts.ProxyAddress,
+	}
+
+	headers := make(map[string]string)
+	for _, h := range opts.Header {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	if opts.UserAgent != "" {
+		headers["User-Agent"] = opts.UserAgent
+		cfg.UserAgent = opts.UserAgent
+	}
+	if opts.Cookie != "" {
+		headers["Cookie"] = opts.Cookie
+	}
+	if len(headers) > 0 {
+		cfg.Headers = headers
+	}
+	return cfg
+}
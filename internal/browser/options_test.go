@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts model.Options
+		want BrowserConfig
+	}{
+		{
+			name: "defaults",
+			opts: model.Options{},
+			want: BrowserConfig{HeadlessMode: true, Timeout: 30, WaitForAlertOnlyTime: 5, TakeScreenshots: true},
+		},
+		{
+			name: "browser flags",
+			opts: model.Options{
+				HeadlessTimeout:     3,
+				HeadlessConcurrency: 4,
+				ChromiumPath:        "/usr/bin/chromium",
+				DisableSandbox:      true,
+				ScreenshotQuality:   95,
+				ProxyAddress:        "http://127.0.0.1:8080",
+			},
+			want: BrowserConfig{
+				HeadlessMode:         true,
+				DisableSandbox:       true,
+				Timeout:              3,
+				WaitForAlertOnlyTime: 1,
+				ChromiumBinaryPath:   "/usr/bin/chromium",
+				TakeScreenshots:      true,
+				ScreenshotQuality:    95,
+				MaxTabs:              4,
+				ProxyServer:          "http://127.0.0.1:8080",
+			},
+		},
+		{
+			name: "request headers",
+			opts: model.Options{
+				Header:    []string{"Authorization: Bearer x", "X-Empty:", "broken"},
+				UserAgent: "dalfox-test",
+				Cookie:    "sid=1",
+			},
+			want: BrowserConfig{
+				HeadlessMode:         true,
+				Timeout:              30,
+				WaitForAlertOnlyTime: 5,
+				TakeScreenshots:      true,
+				UserAgent:            "dalfox-test",
+				Headers: map[string]string{
+					"Authorization": "Bearer x",
+					"X-Empty":       "",
+					"User-Agent":    "dalfox-test",
+					"Cookie":        "sid=1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConfigFromOptions(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfigFromOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	for in, want := range map[string]string{"": EngineChromium, " Chromium ": EngineChromium, "firefox": EngineFirefox, "WebKit": EngineWebKit} {
+		if got, err := ParseEngine(in); err != nil || got != want {
+			t.Errorf("ParseEngine(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"chrom", "firefx", "edge"} {
+		if _, err := ParseEngine(in); !errors.Is(err, ErrInvalidEngine) {
+			t.Errorf("ParseEngine(%q) error = %v, want ErrInvalidEngine", in, err)
+		}
+	}
+}
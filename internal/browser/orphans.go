@@ -0,0 +1,84 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// recordBrowserPID remembers the PID of the Chrome just launched, so KillOrphans
+// can find it once the browser was discarded. Callers must hold browserMutex.
+func (m *Manager) recordBrowserPID() {
+	c := chromedp.FromContext(m.browserCtx)
+	if c == nil || c.Browser == nil || c.Browser.Process() == nil {
+		return
+	}
+	m.browserPID = c.Browser.Process().Pid
+	m.launchedPIDs = append(m.launchedPIDs, m.browserPID)
+}
+
+// restartBrowser tears down the shared browser, its sessions and its allocator
+// and prepares new ones; Chrome is launched again by the next tab. Validations
+// still running on the old browser fail.
+func (m *Manager) restartBrowser() {
+	m.closeSessions()
+
+	m.browserMutex.Lock()
+	defer m.browserMutex.Unlock()
+	if m.cancelAlloc == nil {
+		return // shut down meanwhile
+	}
+	m.cancelBrowser()
+	m.cancelAlloc()
+	m.browserPID = 0
+	m.allocCtx, m.cancelAlloc = chromedp.NewExecAllocator(context.Background(), m.allocatorOptions()...)
+	m.browserCtx, m.cancelBrowser = chromedp.NewContext(m.allocCtx)
+	m.browserStarted = false
+}
+
+// recoverValidation turns a panic of a validation into a failed result. The
+// browser is restarted since the panicking tab may have left Chrome in an
+// unknown state; its processes are reaped by KillOrphans if they linger.
+func (m *Manager) recoverValidation(result **ValidationResult, start time.Time) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	m.logger().Error("browser: validation panicked, restarting the browser", "panic", r, "stack", string(debug.Stack()))
+	m.restartBrowser()
+	*result = &ValidationResult{
+		IsVulnerable:       false,
+		ExecutionDetected:  false,
+		Error:              fmt.Errorf("%w: %v", ErrValidationPanic, r),
+		ValidationDuration: time.Since(start),
+	}
+}
+
+// KillOrphans kills the Chrome processes the manager launched but no longer
+// uses, such as browsers discarded after a panic or left behind by Shutdown,
+// along with their renderer and helper processes. It returns how many
+// processes were killed. The browser in use is left alone. Only Linux is
+// supported; elsewhere it returns errors.ErrUnsupported.
+func (m *Manager) KillOrphans() (int, error) {
+	m.browserMutex.Lock()
+	defer m.browserMutex.Unlock()
+
+	var orphans []int
+	for _, pid := range m.launchedPIDs {
+		if pid != m.browserPID || !m.browserStarted {
+			orphans = append(orphans, pid)
+		}
+	}
+	killed, remaining, err := killProcessTrees(orphans)
+	if err != nil {
+		return killed, err
+	}
+	if m.browserStarted && m.browserPID != 0 {
+		remaining = append(remaining, m.browserPID)
+	}
+	m.launchedPIDs = remaining
+	return killed, nil
+}
@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// chromeMarker is a flag chromedp passes to the browser process only, telling
+// a Chrome we launched from an unrelated process that reused its PID
+const chromeMarker = "--remote-debugging-port"
+
+// killProcessTrees kills every root that is still a Chrome we launched, then
+// its descendants. It returns how many processes were killed and the roots that
+// survived the kill, to try again later.
+func killProcessTrees(roots []int) (int, []int, error) {
+	if len(roots) == 0 {
+		return 0, nil, nil
+	}
+	parents, err := processParents("/proc")
+	if err != nil {
+		return 0, roots, err
+	}
+	killed := 0
+	var remaining []int
+	for _, root := range roots {
+		if _, alive := parents[root]; !alive || !isChromeProcess("/proc", root) {
+			continue // exited, its PID may since belong to someone else
+		}
+		// collect the tree before killing its root, children are reparented after
+		tree := append([]int{root}, descendants(root, parents)...)
+		failed := false
+		for _, pid := range tree {
+			if err := syscall.Kill(pid, syscall.SIGKILL); err == nil {
+				killed++
+			} else if pid == root && err != syscall.ESRCH {
+				failed = true
+			}
+		}
+		if failed {
+			remaining = append(remaining, root)
+		}
+	}
+	return killed, remaining, nil
+}
+
+// processParents maps the PID of every process under procDir to its parent PID
+func processParents(procDir string) (map[int]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int]int)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(procDir, e.Name(), "stat"))
+		if err != nil {
+			continue // exited while listing
+		}
+		// the command name in parentheses may contain spaces; the fields after
+		// it are "state ppid ..."
+		i := bytes.LastIndexByte(stat, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[i+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			parents[pid] = ppid
+		}
+	}
+	return parents, nil
+}
+
+// isChromeProcess reports whether pid was started with chromeMarker
+func isChromeProcess(procDir string, pid int) bool {
+	cmdline, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"))
+	return err == nil && bytes.Contains(cmdline, []byte(chromeMarker))
+}
+
+// descendants returns the PIDs below root in the process tree given by parents
+func descendants(root int, parents map[int]int) []int {
+	children := make(map[int][]int)
+	for pid, ppid := range parents {
+		children[ppid] = append(children[ppid], pid)
+	}
+	var out []int
+	queue := children[root]
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		out = append(out, pid)
+		queue = append(queue, children[pid]...)
+	}
+	return out
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package browser
+
+import "errors"
+
+// killProcessTrees needs /proc to find Chrome's helper processes
+func killProcessTrees(roots []int) (int, []int, error) {
+	if len(roots) == 0 {
+		return 0, nil, nil
+	}
+	return 0, roots, errors.ErrUnsupported
+}
@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_descendants(t *testing.T) {
+	parents := map[int]int{1: 0, 10: 1, 11: 10, 12: 10, 13: 12, 20: 1}
+	got := descendants(10, parents)
+	slices.Sort(got)
+	if want := []int{11, 12, 13}; !reflect.DeepEqual(got, want) {
+		t.Errorf("descendants(10) = %v, want %v", got, want)
+	}
+	if got := descendants(20, parents); len(got) != 0 {
+		t.Errorf("descendants(20) = %v, want none", got)
+	}
+}
+
+func TestManager_KillOrphans(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := NewManager(BrowserConfig{}).KillOrphans(); err != nil {
+			t.Fatalf("KillOrphans() without launched browsers error = %v", err)
+		}
+		t.Skip("process trees are only walked on linux")
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	// a fake Chrome carrying the chromedp flag, with a helper child
+	chrome := exec.Command(sh, "-c", "sleep 30 & wait", "chrome", chromeMarker+"=0")
+	if err := chrome.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- chrome.Wait() }()
+	// an unrelated process that must survive
+	other := exec.Command("sleep", "30")
+	if err := other.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer other.Process.Kill()
+	time.Sleep(100 * time.Millisecond) // let sh fork the helper
+
+	m := NewManager(BrowserConfig{})
+	m.launchedPIDs = []int{chrome.Process.Pid, other.Process.Pid}
+	killed, err := m.KillOrphans()
+	if err != nil {
+		t.Fatalf("KillOrphans() error = %v", err)
+	}
+	if killed != 2 {
+		t.Errorf("KillOrphans() killed %d processes, want the fake Chrome and its helper", killed)
+	}
+	select {
+	case err := <-exited:
+		if err == nil || !strings.Contains(err.Error(), "killed") {
+			t.Errorf("fake Chrome exited with %v, want killed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fake Chrome still running")
+	}
+	if err := other.Process.Signal(syscall.Signal(0)); err != nil {
+		t.Errorf("unrelated process was killed: %v", err)
+	}
+	if len(m.launchedPIDs) != 0 {
+		t.Errorf("launchedPIDs = %v after reaping, want none", m.launchedPIDs)
+	}
+}
+
+func TestManager_ValidatePayloadPanic(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{})
+	m.OnExecution(func(ExecutionProof) { panic("callback bug") })
+	res := m.ValidatePayload("", "data:text/html,<script>alert(1)</script>", "panic-test", "html")
+	if !errors.Is(res.Error, ErrValidationPanic) || !strings.Contains(res.Error.Error(), "callback bug") {
+		t.Fatalf("ValidatePayload() error = %v, want ErrValidationPanic", res.Error)
+	}
+	if _, err := m.KillOrphans(); err != nil {
+		t.Errorf("KillOrphans() error = %v", err)
+	}
+
+	// the restarted browser keeps validating
+	m.onExecution = nil
+	if res := m.ValidatePayload("", "data:text/html,<script>alert(1)</script>", "panic-test", "html"); !res.ExecutionDetected {
+		t.Errorf("ValidatePayload() after a panic = %+v, want execution", res)
+	}
+}
@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Pre-action types, see Action
+const (
+	ActionNavigate = "navigate"
+	ActionClick    = "click"
+	ActionType     = "type"
+	ActionWait     = "wait"
+)
+
+// Action is a step of BrowserConfig.PreActions
+type Action struct {
+	Type     string `json:"type"`               // one of the Action constants
+	URL      string `json:"url,omitempty"`      // page loaded by a navigate step
+	Selector string `json:"selector,omitempty"` // element a click, type or wait step acts on
+	Text     string `json:"text,omitempty"`     // keys a type step sends to Selector
+}
+
+// task returns the chromedp action performing a. Elements are waited for until
+// they are visible before being clicked or typed into.
+func (a Action) task() (chromedp.Action, error) {
+	typ := strings.ToLower(a.Type)
+	if typ == ActionNavigate {
+		if a.URL == "" {
+			return nil, errors.New("navigate needs a URL")
+		}
+		return chromedp.Navigate(a.URL), nil
+	}
+	if typ != ActionClick && typ != ActionType && typ != ActionWait {
+		return nil, fmt.Errorf("unknown type %q", a.Type)
+	}
+	if a.Selector == "" {
+		return nil, fmt.Errorf("%s needs a selector", typ)
+	}
+	switch typ {
+	case ActionClick:
+		return chromedp.Click(a.Selector, chromedp.NodeVisible), nil
+	case ActionType:
+		return chromedp.SendKeys(a.Selector, a.Text, chromedp.NodeVisible), nil
+	default:
+		return chromedp.WaitVisible(a.Selector), nil
+	}
+}
+
+// runPreActions performs PreActions in order in the tab of ctx, each bounded by
+// Timeout. Errors name the failing step, counted from 1.
+func (m *Manager) runPreActions(ctx context.Context) error {
+	for i, a := range m.config.PreActions {
+		task, err := a.task()
+		if err == nil {
+			err = m.runWithTimeout(ctx, task)
+		}
+		if err != nil {
+			return fmt.Errorf("pre-action %d (%s): %w", i+1, a.Type, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAction_task(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  Action
+		wantErr string
+	}{
+		{name: "navigate", action: Action{Type: "navigate", URL: "http://example.com/"}},
+		{name: "click", action: Action{Type: "Click", Selector: "#accept"}},
+		{name: "type", action: Action{Type: "type", Selector: "#user", Text: "admin"}},
+		{name: "wait", action: Action{Type: "wait", Selector: "#dashboard"}},
+		{name: "navigate without url", action: Action{Type: "navigate"}, wantErr: "needs a URL"},
+		{name: "click without selector", action: Action{Type: "click"}, wantErr: "needs a selector"},
+		{name: "unknown", action: Action{Type: "hover", Selector: "#menu"}, wantErr: "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := tt.action.task()
+			if tt.wantErr == "" {
+				if err != nil || task == nil {
+					t.Errorf("task() = %v, %v, want an action", task, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("task() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_ValidatePayloadPreActions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			fmt.Fprint(w, `<input id="user"><button id="go" onclick="document.cookie = 'user=' + user.value; alert('welcome')">go</button>`)
+		default:
+			// the sink is only reachable once logged in
+			if c, err := r.Cookie("user"); err == nil && c.Value == "admin" {
+				fmt.Fprintf(w, "<p>%s</p>", r.URL.Query().Get("q"))
+			}
+		}
+	}))
+	defer srv.Close()
+	target := srv.URL + "/search?q=%3Cscript%3Ealert(document.cookie)%3C/script%3E"
+
+	m := newTestManager(t, BrowserConfig{})
+	if res := m.ValidatePayload("", target, "<script>alert(document.cookie)</script>", "html"); res.ExecutionDetected {
+		t.Fatalf("ValidatePayload() without pre-actions = %+v, want no execution", res)
+	}
+
+	m = newTestManager(t, BrowserConfig{PreActions: []Action{
+		{Type: ActionNavigate, URL: srv.URL + "/login"},
+		{Type: ActionType, Selector: "#user", Text: "admin"},
+		{Type: ActionClick, Selector: "#go"},
+	}})
+	res := m.ValidatePayload("", target, "<script>alert(document.cookie)</script>", "html")
+	if !res.ExecutionDetected || res.ExecutionProofs[0].Evidence != "user=admin" {
+		t.Errorf("ValidatePayload() with pre-actions = %+v, want only the sink's dialog", res)
+	}
+
+	m = newTestManager(t, BrowserConfig{Timeout: 1, PreActions: []Action{{Type: ActionClick, Selector: "#missing"}}})
+	res = m.ValidatePayload("", target, "", "html")
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "pre-action 1 (click)") {
+		t.Errorf("ValidatePayload() with a failing pre-action error = %v", res.Error)
+	}
+}
@@ -0,0 +1,55 @@
+package browser
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// evidenceDirs are the subdirectories of the snapshot directory holding
+// evidence files: one per screenshot format plus the rendered DOM and SVGs
+var evidenceDirs = []string{FormatJPG, FormatPNG, FormatWebP, FormatAVIF, "html", "svg"}
+
+// PurgeSnapshots deletes the evidence files under the snapshot directory, i.e.
+// screenshots of every format, rendered DOMs and annotated SVGs, last modified
+// more than olderThan ago, and returns how many were removed. Files elsewhere
+// in the directory are left alone. Deleting continues past failures; the first
+// one is returned.
+func (m *Manager) PurgeSnapshots(olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	root := m.snapshotDir()
+
+	m.screenshotsMu.Lock()
+	defer m.screenshotsMu.Unlock()
+
+	removed := 0
+	var firstErr error
+	for _, dir := range evidenceDirs {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(root, dir, e.Name())); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, firstErr
+}
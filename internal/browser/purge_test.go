@@ -0,0 +1,63 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManager_PurgeSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	files := map[string]bool{ // path -> purged
+		"jpg/old.jpg":   true,
+		"webp/old.webp": true,
+		"html/old.html": true,
+		"jpg/new.jpg":   false,
+		"notes.txt":     false,
+		"other/old.jpg": false,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(name) != "new.jpg" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	m := NewManager(BrowserConfig{SnapshotDir: dir})
+	n, err := m.PurgeSnapshots(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeSnapshots() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("PurgeSnapshots() removed %d files, want 3", n)
+	}
+	for name, purged := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists == purged {
+			t.Errorf("%s exists = %v after purge, want %v", name, exists, !purged)
+		}
+	}
+
+	// Initialize applies SnapshotRetention
+	if err := os.Chtimes(filepath.Join(dir, "jpg/new.jpg"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	m = NewManager(BrowserConfig{SnapshotDir: dir, SnapshotRetention: 24})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer m.Shutdown()
+	if _, err := os.Stat(filepath.Join(dir, "jpg/new.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Initialize() with SnapshotRetention kept an expired snapshot")
+	}
+}
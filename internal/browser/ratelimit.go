@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"context"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter is a token bucket per host limiting how often tabs navigate to it.
+// Every bucket holds at most one token, so navigations to a host are spread
+// evenly instead of bursting.
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket is the state of one host; tokens goes negative while navigations
+// are waiting for their turn
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newHostLimiter returns a limiter allowing perSecond navigations per host, or
+// nil when perSecond is not positive
+func newHostLimiter(perSecond float64) *hostLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &hostLimiter{rate: perSecond, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until a navigation to url may start or ctx is done. Local
+// documents and URLs without a host are never limited; neither is a nil limiter.
+func (l *hostLimiter) wait(ctx context.Context, url string) error {
+	if l == nil || isLocalDocument(url) {
+		return nil
+	}
+	u, err := neturl.Parse(url)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	host := strings.ToLower(u.Host)
+
+	l.mu.Lock()
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &tokenBucket{tokens: 1, last: now}
+		l.buckets[host] = b
+	}
+	b.tokens = min(1, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	// take the token now; a negative balance is the backlog ahead of us
+	b.tokens--
+	delay := time.Duration(-b.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(host)
+		return ctx.Err()
+	}
+}
+
+// refund gives back the token a cancelled wait took from host's bucket, so the
+// navigations queued behind it are not delayed for nothing
+func (l *hostLimiter) refund(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.buckets[host]
+	b.tokens = min(1, b.tokens+now.Sub(b.last).Seconds()*l.rate+1)
+	b.last = now
+}
@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_hostLimiter(t *testing.T) {
+	if l := newHostLimiter(0); l != nil {
+		t.Fatalf("newHostLimiter(0) = %v, want nil", l)
+	}
+	var disabled *hostLimiter
+	if err := disabled.wait(context.Background(), "http://example.com/"); err != nil {
+		t.Fatalf("nil limiter wait() error = %v", err)
+	}
+
+	l := newHostLimiter(20) // one navigation per 50ms
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(ctx, "http://example.com/?q="+string(rune('a'+i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 navigations to one host took %v, want at least 100ms", elapsed)
+	}
+
+	// other hosts and local documents have their own budget
+	start = time.Now()
+	_ = l.wait(ctx, "http://other.example/")
+	_ = l.wait(ctx, "data:text/html,x")
+	_ = l.wait(ctx, "data:text/html,y")
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("navigations to a fresh host were delayed by %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_ = l.wait(ctx, "http://slow.example/")
+	if err := l.wait(cancelled, "http://slow.example/"); err != context.Canceled {
+		t.Errorf("wait() on a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func Test_hostLimiter_cancelledWaitRefunds(t *testing.T) {
+	l := newHostLimiter(10) // one navigation per 100ms
+	ctx := context.Background()
+	if err := l.wait(ctx, "http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a waiter queued behind the first navigation gives up early
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(cancelled, "http://example.com/"); err != context.DeadlineExceeded {
+		t.Fatalf("wait() on an expiring context = %v, want context.DeadlineExceeded", err)
+	}
+
+	// the next caller only waits out the first navigation's interval
+	start := time.Now()
+	if err := l.wait(ctx, "http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("wait() after a cancelled waiter took %v, want under 100ms", elapsed)
+	}
+}
@@ -0,0 +1,296 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/image/draw"
+)
+
+// Screenshot formats accepted by BrowserConfig.ScreenshotFormat. The value is
+// also used as file extension and as subdirectory of the snapshot directory.
+const (
+	FormatJPG  = "jpg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+)
+
+// FormatAuto saves each screenshot as PNG or JPEG, whichever suits its content,
+// see flatImage. It is never used as a file extension.
+const FormatAuto = "auto"
+
+// flatImage samples at most flatSampleGrid x flatSampleGrid pixels and calls
+// an image flat when they hold no more than flatMaxColors distinct colors
+const (
+	flatSampleGrid = 64
+	flatMaxColors  = 256
+)
+
+// defaultScreenshotQuality is used when BrowserConfig.ScreenshotQuality is unset
+const defaultScreenshotQuality = 90
+
+// writeScreenshot writes data to path unless an identical screenshot was
+// already written, in which case the existing file is reused. It returns the
+// path that holds data. Captures of the same rendered alert page are common
+// across payloads, so this keeps big scans from filling the disk.
+func (m *Manager) writeScreenshot(path string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+
+	m.screenshotsMu.Lock()
+	defer m.screenshotsMu.Unlock()
+	if existing, ok := m.screenshots[sum]; ok {
+		if _, err := os.Stat(existing); err == nil {
+			return existing, nil
+		}
+	}
+	if err := writeEvidenceFile(path, data); err != nil {
+		return "", err
+	}
+	m.stats.screenshots.Add(1)
+	m.screenshots[sum] = path
+	return path, nil
+}
+
+// writeEvidenceFile writes data to path, creating its snapshot subdirectory
+// on first use
+func writeEvidenceFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// screenshotQuality returns the configured quality clamped to 1-100
+func (m *Manager) screenshotQuality() int {
+	return clampQuality(m.config.ScreenshotQuality)
+}
+
+// clampQuality maps 0 to the default and clamps other values to the valid 1-100 range
+func clampQuality(q int) int {
+	switch {
+	case q == 0:
+		return defaultScreenshotQuality
+	case q < 1:
+		return 1
+	case q > 100:
+		return 100
+	default:
+		return q
+	}
+}
+
+// takeScreenshot captures the element matched by ScreenshotSelector, or the full
+// page when no selector is configured or it matches nothing.
+func (m *Manager) takeScreenshot(ctx context.Context, format string, quality int) ([]byte, error) {
+	var buf []byte
+	if sel := m.config.ScreenshotSelector; sel != "" {
+		// check for a match first; Screenshot would otherwise wait for the node until timeout
+		var nodes []*cdp.Node
+		if err := m.runWithTimeout(ctx, chromedp.Nodes(sel, &nodes, chromedp.AtLeast(0))); err == nil && len(nodes) > 0 {
+			if err := m.runWithTimeout(ctx, chromedp.Screenshot(sel, &buf, chromedp.NodeVisible)); err == nil {
+				return buf, nil
+			}
+		}
+	}
+
+	// chromedp returns PNG bytes only at quality 100, JPEG otherwise; formats
+	// other than JPEG, and KeepOriginalPNG, need a lossless capture
+	captureQuality := quality
+	if format != FormatJPG || m.config.KeepOriginalPNG {
+		captureQuality = 100
+	}
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&buf, captureQuality)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// pngMagic is the signature every PNG file starts with
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// normalizeScreenshotFormat maps user input to one of the Format constants,
+// defaulting to JPEG for empty or unknown values.
+func normalizeScreenshotFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "png":
+		return FormatPNG
+	case "webp":
+		return FormatWebP
+	case "avif":
+		return FormatAVIF
+	case "auto":
+		return FormatAuto
+	default:
+		return FormatJPG
+	}
+}
+
+// encodedScreenshotFormat returns the format encodeScreenshot actually produces
+// for a requested format. FormatAuto is only decided per screenshot and reported
+// as FormatJPG, the choice for typical page content.
+func encodedScreenshotFormat(format string) string {
+	switch f := normalizeScreenshotFormat(format); f {
+	case FormatPNG:
+		return f
+	case FormatWebP, FormatAVIF:
+		if _, _, ok := findEncoder(f); ok {
+			return f
+		}
+	}
+	return FormatJPG
+}
+
+// encodeScreenshot re-encodes a captured screenshot to format and returns the
+// encoded bytes along with the format actually produced. quality applies to lossy
+// formats. The standard library has no WebP or AVIF encoder, so those are encoded
+// with cwebp or avifenc when installed; otherwise, or when the encoder fails, the
+// screenshot falls back to JPEG with a warning logged once per format. FormatAuto
+// produces PNG for flat images, e.g. an alert over a white page, where it is the
+// smaller encoding, and JPEG for photographic content.
+func (m *Manager) encodeScreenshot(raw []byte, format string, quality int) ([]byte, string, error) {
+	switch f := normalizeScreenshotFormat(format); f {
+	case FormatWebP, FormatAVIF:
+		img, err := encodeExternal(raw, f, quality)
+		if err == nil {
+			return img, f, nil
+		}
+		m.warnFallback(f, err)
+		jpg, err := convertPNGtoJPG(raw, quality)
+		if err != nil {
+			return nil, "", err
+		}
+		return jpg, FormatJPG, nil
+	case FormatAuto:
+		img, _, err := image.Decode(bytes.NewReader(raw))
+		if err != nil {
+			return nil, "", err
+		}
+		if !flatImage(img) {
+			jpg, err := encodeJPEG(img, quality)
+			return jpg, FormatJPG, err
+		}
+		if bytes.HasPrefix(raw, pngMagic) {
+			return raw, FormatPNG, nil
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), FormatPNG, nil
+	case FormatPNG:
+		if bytes.HasPrefix(raw, pngMagic) {
+			return raw, FormatPNG, nil
+		}
+		img, _, err := image.Decode(bytes.NewReader(raw))
+		if err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), FormatPNG, nil
+	default:
+		jpg, err := convertPNGtoJPG(raw, quality)
+		if err != nil {
+			return nil, "", err
+		}
+		return jpg, FormatJPG, nil
+	}
+}
+
+// convertPNGtoJPG converts a PNG image bytes to JPEG bytes with given quality (0-100).
+func convertPNGtoJPG(pngBytes []byte, quality int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(pngBytes))
+	if err != nil {
+		return nil, err
+	}
+	return encodeJPEG(img, quality)
+}
+
+// encodeJPEG encodes img as JPEG with given quality (0-100).
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	opts := &jpeg.Options{Quality: quality}
+	if err := jpeg.Encode(&buf, img, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flatImage reports whether img is mostly flat color, judged from a grid of
+// sampled pixels. PNG compresses such images better than JPEG, which also
+// blurs their text; photographs and gradients have too many colors.
+func flatImage(img image.Image) bool {
+	b := img.Bounds()
+	stepX := max(1, b.Dx()/flatSampleGrid)
+	stepY := max(1, b.Dy()/flatSampleGrid)
+	colors := make(map[color.RGBA64]struct{}, flatMaxColors+1)
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			r, g, bl, a := img.At(x, y).RGBA()
+			colors[color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(bl), A: uint16(a)}] = struct{}{}
+			if len(colors) > flatMaxColors {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// limitScreenshot downscales a capture that exceeds MaxScreenshotWidth or
+// MaxScreenshotHeight, see downscaleScreenshot.
+func (m *Manager) limitScreenshot(raw []byte) ([]byte, error) {
+	return downscaleScreenshot(raw, m.config.MaxScreenshotWidth, m.config.MaxScreenshotHeight)
+}
+
+// downscaleScreenshot shrinks raw to fit within maxWidth x maxHeight, keeping
+// the aspect ratio, and returns it as PNG. A limit of zero or less leaves that
+// dimension unbounded. raw is returned unchanged, without being decoded, when it
+// already fits.
+func downscaleScreenshot(raw []byte, maxWidth, maxHeight int) ([]byte, error) {
+	if maxWidth <= 0 && maxHeight <= 0 {
+		return raw, nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	scale := 1.0
+	if maxWidth > 0 && cfg.Width > maxWidth {
+		scale = float64(maxWidth) / float64(cfg.Width)
+	}
+	if maxHeight > 0 && cfg.Height > maxHeight {
+		scale = min(scale, float64(maxHeight)/float64(cfg.Height))
+	}
+	if scale == 1 {
+		return raw, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	w := max(1, int(float64(cfg.Width)*scale+0.5))
+	h := max(1, int(float64(cfg.Height)*scale+0.5))
+	// Catmull-Rom averages the whole footprint of each destination pixel when
+	// shrinking, which keeps text-heavy pages legible
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Rect, src, src.Bounds(), draw.Src, nil)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,298 @@
+package browser
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testPNG returns a small solid PNG image
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 30, B: 30, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testNoisePNG returns a PNG image of random pixels, like a photograph as far as
+// the encoders are concerned
+func testNoisePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func Test_encodeScreenshot(t *testing.T) {
+	raw := testPNG(t, 16, 16)
+	tests := []struct {
+		name       string
+		format     string
+		raw        []byte // defaults to a flat image
+		wantFormat string
+		wantMagic  []byte
+	}{
+		{name: "default jpg", format: "", wantFormat: FormatJPG, wantMagic: []byte{0xFF, 0xD8}},
+		{name: "jpeg alias", format: "JPEG", wantFormat: FormatJPG, wantMagic: []byte{0xFF, 0xD8}},
+		{name: "png passthrough", format: "png", wantFormat: FormatPNG, wantMagic: pngMagic},
+		{name: "webp falls back to jpg", format: "webp", wantFormat: FormatJPG, wantMagic: []byte{0xFF, 0xD8}},
+		{name: "avif falls back to jpg", format: "AVIF", wantFormat: FormatJPG, wantMagic: []byte{0xFF, 0xD8}},
+		{name: "auto keeps flat png", format: "auto", wantFormat: FormatPNG, wantMagic: pngMagic},
+		{name: "auto photo as jpg", format: "auto", raw: testNoisePNG(t, 64, 64), wantFormat: FormatJPG, wantMagic: []byte{0xFF, 0xD8}},
+	}
+	withoutEncoders(t)
+	m := NewManager(BrowserConfig{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := raw
+			if tt.raw != nil {
+				in = tt.raw
+			}
+			got, gotFormat, err := m.encodeScreenshot(in, tt.format, 90)
+			if err != nil {
+				t.Fatalf("encodeScreenshot() error = %v", err)
+			}
+			if gotFormat != tt.wantFormat {
+				t.Errorf("encodeScreenshot() format = %q, want %q", gotFormat, tt.wantFormat)
+			}
+			if !bytes.HasPrefix(got, tt.wantMagic) {
+				t.Errorf("encodeScreenshot() output does not start with %x", tt.wantMagic)
+			}
+		})
+	}
+
+	if _, _, err := m.encodeScreenshot([]byte("not an image"), FormatJPG, 90); err == nil {
+		t.Errorf("encodeScreenshot() with invalid input should fail")
+	}
+}
+
+func Test_flatImage(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want bool
+	}{
+		{name: "solid", raw: testPNG(t, 300, 200), want: true},
+		{name: "noise", raw: testNoisePNG(t, 300, 200), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := png.Decode(bytes.NewReader(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := flatImage(img); got != tt.want {
+				t.Errorf("flatImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the choice holds up: each image gets the smaller of both encodings
+	for _, raw := range [][]byte{testPNG(t, 300, 200), testNoisePNG(t, 300, 200)} {
+		img, _ := png.Decode(bytes.NewReader(raw))
+		jpg, _ := encodeJPEG(img, 90)
+		if flatImage(img) != (len(raw) < len(jpg)) {
+			t.Errorf("flatImage() = %v with PNG %d bytes and JPEG %d bytes", flatImage(img), len(raw), len(jpg))
+		}
+	}
+}
+
+func Test_clampQuality(t *testing.T) {
+	tests := []struct {
+		in   int
+		want int
+	}{
+		{0, defaultScreenshotQuality},
+		{-5, 1},
+		{1, 1},
+		{75, 75},
+		{100, 100},
+		{250, 100},
+	}
+	for _, tt := range tests {
+		if got := clampQuality(tt.in); got != tt.want {
+			t.Errorf("clampQuality(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestManager_writeScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(BrowserConfig{})
+	img := testPNG(t, 4, 4)
+
+	first, err := m.writeScreenshot(filepath.Join(dir, "a.png"), img)
+	if err != nil {
+		t.Fatalf("writeScreenshot() error = %v", err)
+	}
+	second, err := m.writeScreenshot(filepath.Join(dir, "b.png"), img)
+	if err != nil {
+		t.Fatalf("writeScreenshot() error = %v", err)
+	}
+	if second != first {
+		t.Errorf("identical screenshot written to %q, want reuse of %q", second, first)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.png")); !os.IsNotExist(err) {
+		t.Errorf("duplicate screenshot file was written")
+	}
+
+	other, _ := m.writeScreenshot(filepath.Join(dir, "c.png"), testPNG(t, 8, 8))
+	if other != filepath.Join(dir, "c.png") {
+		t.Errorf("different screenshot path = %q, want its own file", other)
+	}
+
+	// a reused file that was removed meanwhile is written again
+	os.Remove(first)
+	again, _ := m.writeScreenshot(filepath.Join(dir, "d.png"), img)
+	if again != filepath.Join(dir, "d.png") {
+		t.Errorf("screenshot after removal = %q, want a new file", again)
+	}
+}
+
+// withoutEncoders hides the external image encoders for the rest of the test
+func withoutEncoders(t *testing.T) {
+	t.Helper()
+	orig := lookPath
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	t.Cleanup(func() { lookPath = orig })
+}
+
+func Test_encodeScreenshot_external(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func([]byte) bool
+	}{
+		{FormatWebP, func(b []byte) bool { return len(b) > 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP" }},
+		{FormatAVIF, func(b []byte) bool { return len(b) > 12 && string(b[4:8]) == "ftyp" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if _, _, ok := findEncoder(tt.format); !ok {
+				t.Skipf("no %s encoder installed", tt.format)
+			}
+			if got := encodedScreenshotFormat(tt.format); got != tt.format {
+				t.Errorf("encodedScreenshotFormat(%q) = %q", tt.format, got)
+			}
+			got, gotFormat, err := NewManager(BrowserConfig{}).encodeScreenshot(testPNG(t, 16, 16), tt.format, 80)
+			if err != nil {
+				t.Fatalf("encodeScreenshot() error = %v", err)
+			}
+			if gotFormat != tt.format || !tt.check(got) {
+				t.Errorf("encodeScreenshot() = %s output that does not look like %s", gotFormat, tt.format)
+			}
+		})
+	}
+}
+
+func Test_downscaleScreenshot(t *testing.T) {
+	raw := testPNG(t, 400, 2000)
+	tests := []struct {
+		name             string
+		maxW, maxH       int
+		wantW, wantH     int
+		wantSameAsSource bool
+	}{
+		{name: "no limits", wantW: 400, wantH: 2000, wantSameAsSource: true},
+		{name: "fits", maxW: 800, maxH: 4000, wantW: 400, wantH: 2000, wantSameAsSource: true},
+		{name: "height limit", maxH: 1000, wantW: 200, wantH: 1000},
+		{name: "width limit", maxW: 100, wantW: 100, wantH: 500},
+		{name: "tighter limit wins", maxW: 200, maxH: 500, wantW: 100, wantH: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := downscaleScreenshot(raw, tt.maxW, tt.maxH)
+			if err != nil {
+				t.Fatalf("downscaleScreenshot() error = %v", err)
+			}
+			if tt.wantSameAsSource && !bytes.Equal(got, raw) {
+				t.Errorf("downscaleScreenshot() re-encoded an image that fits")
+			}
+			img, err := png.Decode(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("downscaleScreenshot() output is not a PNG: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("downscaleScreenshot() size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func Test_downscaleScreenshotAverages(t *testing.T) {
+	// a 2x1 image of a black and a white pixel shrinks to mid grey
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{255, 255, 255, 255})
+	var raw bytes.Buffer
+	if err := png.Encode(&raw, src); err != nil {
+		t.Fatal(err)
+	}
+	got, err := downscaleScreenshot(raw.Bytes(), 1, 0)
+	if err != nil {
+		t.Fatalf("downscaleScreenshot() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("downscaleScreenshot() output is not a PNG: %v", err)
+	}
+	r, g, b, a := img.At(0, 0).RGBA()
+	for _, c := range []uint32{r >> 8, g >> 8, b >> 8} {
+		if c < 120 || c > 135 {
+			t.Errorf("downscaleScreenshot() pixel = %v, want mid grey", img.At(0, 0))
+			break
+		}
+	}
+	if a>>8 != 255 {
+		t.Errorf("downscaleScreenshot() pixel alpha = %d, want 255", a>>8)
+	}
+}
+
+func TestManager_ValidatePayloadScreenshotSelector(t *testing.T) {
+	page := `data:text/html,<body style="margin:0"><div id="target" style="width:120px;height:80px;background:red"></div><script>alert(1)</script></body>`
+	tests := []struct {
+		name     string
+		selector string
+		element  bool // whether the capture is the 120x80 element
+	}{
+		{name: "matching selector", selector: "#target", element: true},
+		{name: "selector without a match falls back to the page", selector: "#missing"},
+		{name: "no selector", selector: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, BrowserConfig{ScreenshotSelector: tt.selector, ScreenshotFormat: FormatPNG, ScreenshotToDiskDisabled: true})
+			res := m.ValidatePayload("", page, "selector-test", "html")
+			if !res.ExecutionDetected || len(res.ExecutionProofs) == 0 {
+				t.Fatalf("ValidatePayload() = %+v, want execution", res)
+			}
+			cfg, err := png.DecodeConfig(bytes.NewReader(res.ExecutionProofs[0].ScreenshotData))
+			if err != nil {
+				t.Fatalf("screenshot is not a PNG: %v", err)
+			}
+			if isElement := cfg.Width == 120 && cfg.Height == 80; isElement != tt.element {
+				t.Errorf("screenshot is %dx%d, want the element only: %v", cfg.Width, cfg.Height, tt.element)
+			}
+		})
+	}
+}
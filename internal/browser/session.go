@@ -0,0 +1,211 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// default pool limits used when BrowserConfig leaves them unset
+const (
+	defaultMaxSessions    = 4
+	defaultSessionIdleTTL = 60 * time.Second
+)
+
+// AcquireSession leases a browser session from the pool. An idle session is
+// reused when available; otherwise a new one is created as long as fewer than
+// MaxSessions exist. When the pool is exhausted it waits for a ReleaseSession
+// call or for ctx to be done. The returned session is a copy taken at lease
+// time; the pool keeps updating its own under the lock.
+func (m *Manager) AcquireSession(ctx context.Context) (BrowserSession, error) {
+	if !m.IsInitialized() {
+		return BrowserSession{}, ErrNotInitialized
+	}
+
+	for {
+		m.sessionsMutex.Lock()
+		for _, s := range m.sessions {
+			if !s.Active {
+				s.Active = true
+				s.LastUsed = time.Now()
+				leased := *s
+				m.sessionsMutex.Unlock()
+				return leased, nil
+			}
+		}
+		// reserve a slot and start Chrome's browser context without the lock, so
+		// releases and lookups are not held up meanwhile
+		create := len(m.sessions)+m.creatingSessions < m.maxSessions()
+		if create {
+			m.creatingSessions++
+		}
+		m.sessionsMutex.Unlock()
+
+		if create {
+			s, err := m.createSession(ctx)
+			var leased BrowserSession
+			if err == nil {
+				leased = *s
+				m.addSession(s)
+			}
+			m.sessionsMutex.Lock()
+			m.creatingSessions--
+			m.sessionsMutex.Unlock()
+			if err != nil {
+				// the slot is free again for a waiting caller
+				m.signalSessionReleased()
+			}
+			return leased, err
+		}
+
+		select {
+		case <-m.sessionReleased:
+		case <-ctx.Done():
+			return BrowserSession{}, ctx.Err()
+		}
+	}
+}
+
+// ReleaseSession returns a leased session to the pool so it can be reused.
+func (m *Manager) ReleaseSession(id string) {
+	m.sessionsMutex.Lock()
+	s, ok := m.sessions[id]
+	if ok {
+		s.Active = false
+		s.LastUsed = time.Now()
+	}
+	m.sessionsMutex.Unlock()
+
+	if ok {
+		m.signalSessionReleased()
+	}
+}
+
+// signalSessionReleased wakes an AcquireSession caller waiting for a free session
+func (m *Manager) signalSessionReleased() {
+	select {
+	case m.sessionReleased <- struct{}{}:
+	default:
+	}
+}
+
+// addSession adds s to the pool under its ID.
+func (m *Manager) addSession(s *BrowserSession) {
+	m.sessionsMutex.Lock()
+	defer m.sessionsMutex.Unlock()
+	m.sessions[s.ID] = s
+}
+
+// getSession returns the pooled session with the given ID. Only its immutable
+// fields, ID, CreatedAt and its browser context, may be read without the lock.
+func (m *Manager) getSession(id string) (*BrowserSession, bool) {
+	m.sessionsMutex.RLock()
+	defer m.sessionsMutex.RUnlock()
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+// removeSession removes the session with the given ID from the pool and closes
+// it. With idleFor above zero, only a session released at least idleFor ago is
+// removed, so one leased again in the meantime survives. It reports whether the
+// session was removed.
+func (m *Manager) removeSession(id string, idleFor time.Duration) bool {
+	m.sessionsMutex.Lock()
+	defer m.sessionsMutex.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || idleFor > 0 && (s.Active || time.Since(s.LastUsed) < idleFor) {
+		return false
+	}
+	s.cancel()
+	delete(m.sessions, id)
+	return true
+}
+
+// listSessions returns a copy of every pooled session, taken at one point in time.
+func (m *Manager) listSessions() []BrowserSession {
+	m.sessionsMutex.RLock()
+	defer m.sessionsMutex.RUnlock()
+	list := make([]BrowserSession, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, *s)
+	}
+	return list
+}
+
+// createSession opens a tab in a fresh browser context.
+func (m *Manager) createSession(ctx context.Context) (*BrowserSession, error) {
+	if err := m.ensureBrowser(); err != nil {
+		return nil, err
+	}
+
+	m.browserMutex.Lock()
+	sctx, cancel := chromedp.NewContext(m.browserCtx, chromedp.WithNewBrowserContext())
+	m.browserMutex.Unlock()
+
+	stop := context.AfterFunc(ctx, cancel)
+	err := chromedp.Run(sctx)
+	if !stop() || err != nil {
+		cancel()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	return &BrowserSession{
+		ID:        fmt.Sprintf("session_%d_%d", now.UnixNano(), m.sessionSeq.Add(1)),
+		CreatedAt: now,
+		Active:    true,
+		LastUsed:  now,
+		ctx:       sctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// reapSessions closes idle sessions older than the configured TTL until done is closed.
+func (m *Manager) reapSessions(done <-chan struct{}) {
+	ttl := m.sessionIdleTTL()
+	interval := ttl / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, s := range m.listSessions() {
+				if !s.Active && time.Since(s.LastUsed) > ttl {
+					m.removeSession(s.ID, ttl)
+				}
+			}
+		}
+	}
+}
+
+// closeSessions cancels and removes every pooled session.
+func (m *Manager) closeSessions() {
+	for _, s := range m.listSessions() {
+		m.removeSession(s.ID, 0)
+	}
+}
+
+func (m *Manager) maxSessions() int {
+	if m.config.MaxSessions > 0 {
+		return m.config.MaxSessions
+	}
+	return defaultMaxSessions
+}
+
+func (m *Manager) sessionIdleTTL() time.Duration {
+	if m.config.SessionIdleTTL > 0 {
+		return time.Duration(m.config.SessionIdleTTL) * time.Second
+	}
+	return defaultSessionIdleTTL
+}
@@ -0,0 +1,57 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// traceMarker is the console.trace message emitted right before a dialog opens
+// or the sentinel is called, see BrowserConfig.CaptureStackTrace.
+const traceMarker = "__dalfox_trace"
+
+// tracerScript wraps the dialog functions and the sentinel so that every call
+// first emits a console.trace. Chrome reports the trace with the JavaScript call
+// stack, and since it is sent before the dialog opens it identifies the code
+// that raised the dialog. The wrappers are named after traceMarker so their own
+// frame can be dropped.
+var tracerScript = fmt.Sprintf(`(function(){
+	var marker = %q;
+	[%q, 'alert', 'confirm', 'prompt'].forEach(function(name){
+		var native = window[name];
+		if (typeof native !== 'function') return;
+		window[name] = function %s(){ console.trace(marker); return native.apply(this, arguments); };
+	});
+})();`, traceMarker, SentinelFunc, traceMarker)
+
+// isTraceEvent reports whether ev is a console.trace emitted by tracerScript
+func isTraceEvent(ev *runtime.EventConsoleAPICalled) bool {
+	return ev.Type == runtime.APITypeTrace && len(ev.Args) > 0 && formatConsoleArgs(ev.Args[:1]) == traceMarker
+}
+
+// formatStackTrace renders st as one "function (url:line:column)" entry per
+// frame, innermost first, with 1-based positions. Frames of the tracer wrappers
+// are skipped; async parents are appended after a "-- description --" line.
+func formatStackTrace(st *runtime.StackTrace) []string {
+	var frames []string
+	for ; st != nil; st = st.Parent {
+		if len(frames) > 0 {
+			frames = append(frames, fmt.Sprintf("-- %s --", st.Description))
+		}
+		for _, f := range st.CallFrames {
+			if f == nil || f.FunctionName == traceMarker {
+				continue
+			}
+			name := f.FunctionName
+			if name == "" {
+				name = "<anonymous>"
+			}
+			url := f.URL
+			if url == "" {
+				url = "<inline>"
+			}
+			frames = append(frames, fmt.Sprintf("%s (%s:%d:%d)", name, url, f.LineNumber+1, f.ColumnNumber+1))
+		}
+	}
+	return frames
+}
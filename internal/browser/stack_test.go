@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func Test_formatStackTrace(t *testing.T) {
+	st := &runtime.StackTrace{
+		CallFrames: []*runtime.CallFrame{
+			{FunctionName: traceMarker, URL: ""},
+			{FunctionName: "", URL: "http://t.test/?q=x", LineNumber: 0, ColumnNumber: 14},
+			{FunctionName: "render", URL: "http://t.test/app.js", LineNumber: 41, ColumnNumber: 2},
+		},
+		Parent: &runtime.StackTrace{
+			Description: "setTimeout",
+			CallFrames:  []*runtime.CallFrame{{FunctionName: "boot", URL: "", LineNumber: 2, ColumnNumber: 0}},
+		},
+	}
+	want := []string{
+		"<anonymous> (http://t.test/?q=x:1:15)",
+		"render (http://t.test/app.js:42:3)",
+		"-- setTimeout --",
+		"boot (<inline>:3:1)",
+	}
+	if got := formatStackTrace(st); !reflect.DeepEqual(got, want) {
+		t.Errorf("formatStackTrace() = %q, want %q", got, want)
+	}
+	if got := formatStackTrace(nil); got != nil {
+		t.Errorf("formatStackTrace(nil) = %q, want nil", got)
+	}
+}
+
+func Test_isTraceEvent(t *testing.T) {
+	marker := []*runtime.RemoteObject{{Type: runtime.TypeString, Value: []byte(`"` + traceMarker + `"`)}}
+	tests := []struct {
+		name string
+		ev   *runtime.EventConsoleAPICalled
+		want bool
+	}{
+		{"tracer", &runtime.EventConsoleAPICalled{Type: runtime.APITypeTrace, Args: marker}, true},
+		{"page trace", &runtime.EventConsoleAPICalled{Type: runtime.APITypeTrace, Args: []*runtime.RemoteObject{{Type: runtime.TypeString, Value: []byte(`"x"`)}}}, false},
+		{"log with marker", &runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: marker}, false},
+		{"no args", &runtime.EventConsoleAPICalled{Type: runtime.APITypeTrace}, false},
+	}
+	for _, tt := range tests {
+		if got := isTraceEvent(tt.ev); got != tt.want {
+			t.Errorf("%s: isTraceEvent() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTabState_stackTraces(t *testing.T) {
+	s := newTabState()
+	s.setTrace([]string{"payload"})
+	s.addDialog(&page.EventJavascriptDialogOpening{Message: "1"})
+	s.setTrace([]string{"site"})
+	s.addDialog(&page.EventJavascriptDialogOpening{Message: "2"})
+
+	dialog, latest := s.stackTraces()
+	if !reflect.DeepEqual(dialog, []string{"payload"}) {
+		t.Errorf("dialog trace = %q, want the trace before the first dialog", dialog)
+	}
+	if !reflect.DeepEqual(latest, []string{"site"}) {
+		t.Errorf("latest trace = %q, want %q", latest, []string{"site"})
+	}
+}
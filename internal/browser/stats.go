@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are aggregate counters of a Manager since it was created, see
+// Manager.Stats. A rising share of navigation failures mid-scan usually means
+// Chrome is flaking or the target started rate limiting. Manager.Collectors
+// exposes the same counters to Prometheus.
+type Stats struct {
+	Validations        uint64        `json:"validations"`         // validations that reached a tab, whatever their outcome
+	Executions         uint64        `json:"executions"`          // validations that confirmed execution
+	NavigationFailures uint64        `json:"navigation-failures"` // validations whose page did not load, retries included
+	AverageDuration    time.Duration `json:"average-duration"`    // mean ValidationDuration of the validations
+	ScreenshotsWritten uint64        `json:"screenshots-written"` // files written, identical captures reuse one file
+}
+
+// validationStats holds the counters behind Stats, updated atomically by
+// concurrent validations
+type validationStats struct {
+	validations   atomic.Uint64
+	executions    atomic.Uint64
+	navFailures   atomic.Uint64
+	screenshots   atomic.Uint64
+	totalDuration atomic.Int64 // nanoseconds
+
+	// durationBuckets counts validations per bucket of durationBuckets, plus
+	// one slot for longer ones; navErrors counts navigation errors per type
+	durationBuckets [len(durationBuckets) + 1]atomic.Uint64
+	navErrors       map[string]uint64
+	navErrorsMu     sync.Mutex
+}
+
+// record counts a finished validation
+func (s *validationStats) record(result *ValidationResult) {
+	s.validations.Add(1)
+	s.totalDuration.Add(int64(result.ValidationDuration))
+	s.observeDuration(result.ValidationDuration)
+	if result.ExecutionDetected {
+		s.executions.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the validation counters. Counters are read one
+// by one, so a snapshot taken during a scan may be off by in-flight validations.
+func (m *Manager) Stats() Stats {
+	s := Stats{
+		Validations:        m.stats.validations.Load(),
+		Executions:         m.stats.executions.Load(),
+		NavigationFailures: m.stats.navFailures.Load(),
+		ScreenshotsWritten: m.stats.screenshots.Load(),
+	}
+	if s.Validations > 0 {
+		s.AverageDuration = time.Duration(m.stats.totalDuration.Load() / int64(s.Validations))
+	}
+	return s
+}
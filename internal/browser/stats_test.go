@@ -0,0 +1,40 @@
+package browser
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManager_Stats(t *testing.T) {
+	m := NewManager(BrowserConfig{})
+	if got := m.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() of a new manager = %+v, want zero", got)
+	}
+
+	m.stats.record(&ValidationResult{ExecutionDetected: true, ValidationDuration: 3 * time.Second})
+	m.stats.record(&ValidationResult{ValidationDuration: time.Second})
+	m.stats.navFailures.Add(1)
+	m.stats.record(&ValidationResult{ValidationDuration: 2 * time.Second})
+
+	img := testPNG(t, 4, 4)
+	dir := t.TempDir()
+	_, _ = m.writeScreenshot(filepath.Join(dir, "a.png"), img)
+	_, _ = m.writeScreenshot(filepath.Join(dir, "b.png"), img) // reuses a.png
+
+	want := Stats{Validations: 3, Executions: 1, NavigationFailures: 1, AverageDuration: 2 * time.Second, ScreenshotsWritten: 1}
+	if got := m.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestManager_StatsValidation(t *testing.T) {
+	m := newTestManager(t, BrowserConfig{TakeScreenshots: false})
+	m.ValidatePayload("", "data:text/html,<script>alert(1)</script>", "stats-test", "html")
+	m.ValidatePayload("", "http://invalid.invalid/", "stats-test", "html")
+
+	got := m.Stats()
+	if got.Validations != 2 || got.Executions != 1 || got.NavigationFailures != 1 || got.AverageDuration <= 0 {
+		t.Errorf("Stats() = %+v, want 2 validations, 1 execution and 1 navigation failure", got)
+	}
+}
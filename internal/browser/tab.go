@@ -0,0 +1,346 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// tabState collects the events of the validation currently running in a tab
+type tabState struct {
+	dialogCh chan *page.EventJavascriptDialogOpening
+	console  *consoleCollector
+	network  networkTracker
+
+	mu      sync.Mutex
+	dialogs []string // evidence of every dialog seen, in order
+	expect  string   // when set, only dialogs mentioning it belong to the payload
+	post    *postRequest
+	sent    *postRequest // POST override applied to the document request
+	req     *documentRequest
+	doc     *documentResponse
+	waitSec int // overrides BrowserConfig.WaitForAlertOnlyTime when positive
+
+	authTried map[fetch.RequestID]bool // requests already answered with credentials
+
+	trace       []string // stack of the latest traced dialog or sentinel call
+	dialogTrace []string // stack that raised the first dialog of the validation
+}
+
+// documentRequest is the request that loaded the main document, as the browser
+// reported it before a POST override was applied
+type documentRequest struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+// firstAuthAttempt reports whether request id is challenged for the first time
+// and records the attempt
+func (s *tabState) firstAuthAttempt(id fetch.RequestID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.authTried[id] {
+		return false
+	}
+	if s.authTried == nil {
+		s.authTried = make(map[fetch.RequestID]bool)
+	}
+	s.authTried[id] = true
+	return true
+}
+
+// setRequest records the main document request. Requests seen after the
+// document response belong to iframes and are ignored; until then a later
+// request replaces the earlier one, so after redirects the last hop is kept.
+func (s *tabState) setRequest(e *network.EventRequestWillBeSent) {
+	if e.Type != network.ResourceTypeDocument || e.Request == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc != nil {
+		return
+	}
+	headers := make(map[string]string, len(e.Request.Headers))
+	for k, v := range e.Request.Headers {
+		headers[k] = fmt.Sprint(v)
+	}
+	var body strings.Builder
+	for _, entry := range e.Request.PostDataEntries {
+		if b, err := base64.StdEncoding.DecodeString(entry.Bytes); err == nil {
+			body.Write(b)
+		}
+	}
+	s.req = &documentRequest{
+		Method:  e.Request.Method,
+		URL:     e.Request.URL,
+		Headers: headers,
+		Body:    body.String(),
+	}
+}
+
+// request returns the main document request as it was sent, with a POST
+// override applied, or nil if none was seen
+func (s *tabState) request() *documentRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.req == nil {
+		return nil
+	}
+	req := *s.req
+	if s.sent != nil {
+		req.Method = "POST"
+		req.Body = s.sent.Body
+		headers := make(map[string]string, len(req.Headers)+1)
+		for k, v := range req.Headers {
+			if !strings.EqualFold(k, "Content-Type") {
+				headers[k] = v
+			}
+		}
+		headers["Content-Type"] = s.sent.contentType()
+		req.Headers = headers
+	}
+	return &req
+}
+
+// documentResponse is the response that delivered the main document
+type documentResponse struct {
+	RequestID network.RequestID
+	URL       string
+	Status    int
+	Headers   map[string]string
+}
+
+// setDocument records the first document response of the validation. Later
+// document responses belong to iframes and are ignored.
+func (s *tabState) setDocument(e *network.EventResponseReceived) {
+	if e.Type != network.ResourceTypeDocument || e.Response == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc != nil {
+		return
+	}
+	headers := make(map[string]string, len(e.Response.Headers))
+	for k, v := range e.Response.Headers {
+		headers[k] = fmt.Sprint(v)
+	}
+	s.doc = &documentResponse{
+		RequestID: e.RequestID,
+		URL:       e.Response.URL,
+		Status:    int(e.Response.Status),
+		Headers:   headers,
+	}
+}
+
+// document returns the recorded main document response, if any
+func (s *tabState) document() *documentResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc
+}
+
+// takePOST returns the pending POST override and clears it so that it only
+// applies to the first document request.
+func (s *tabState) takePOST() *postRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post := s.post
+	s.post = nil
+	if post != nil {
+		s.sent = post
+	}
+	return post
+}
+
+// pendingPOST returns the pending POST override without clearing it
+func (s *tabState) pendingPOST() *postRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.post
+}
+
+// setPOST arms a POST override for the next document request
+func (s *tabState) setPOST(post *postRequest) {
+	s.mu.Lock()
+	s.post = post
+	s.mu.Unlock()
+}
+
+// hasPOST reports whether a POST override is pending
+func (s *tabState) hasPOST() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.post != nil
+}
+
+// expectDialog restricts the dialogs attributed to the validation to those whose
+// message or prompt default contains token.
+func (s *tabState) expectDialog(token string) {
+	s.mu.Lock()
+	s.expect = token
+	s.mu.Unlock()
+}
+
+// matches reports whether dlg belongs to the validation rather than to the site
+func (s *tabState) matches(dlg *page.EventJavascriptDialogOpening) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expect == "" || strings.Contains(dlg.Message, s.expect) || strings.Contains(dlg.DefaultPrompt, s.expect)
+}
+
+// addDialog records a dialog and returns how many were seen so far
+func (s *tabState) addDialog(dlg *page.EventJavascriptDialogOpening) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialogs = append(s.dialogs, dialogEvidence(dlg))
+	if len(s.dialogs) == 1 {
+		// the trace is emitted right before the dialog opens, so it is this dialog's
+		s.dialogTrace = s.trace
+	}
+	return len(s.dialogs)
+}
+
+// setTrace records the stack of a traced dialog or sentinel call
+func (s *tabState) setTrace(frames []string) {
+	s.mu.Lock()
+	s.trace = frames
+	s.mu.Unlock()
+}
+
+// stackTraces returns the stack that raised the first dialog and the latest one
+func (s *tabState) stackTraces() (dialog, latest []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dialogTrace, s.trace
+}
+
+// dialogSummary returns the number of dialogs and their distinct messages joined
+// in order of appearance.
+func (s *tabState) dialogSummary() (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]struct{}, len(s.dialogs))
+	var distinct []string
+	for _, msg := range s.dialogs {
+		if _, ok := seen[msg]; ok {
+			continue
+		}
+		seen[msg] = struct{}{}
+		distinct = append(distinct, msg)
+	}
+	return len(s.dialogs), strings.Join(distinct, " | ")
+}
+
+func newTabState() *tabState {
+	return &tabState{
+		dialogCh: make(chan *page.EventJavascriptDialogOpening, 1),
+		console:  &consoleCollector{},
+	}
+}
+
+// tabRouter forwards the events of a tab to the tabState of the validation that
+// owns the tab at the moment. chromedp listeners cannot be removed, so a tab that
+// runs several validations installs one router and swaps its state instead.
+type tabRouter struct {
+	mu  sync.Mutex
+	cur *tabState
+}
+
+// set makes state the receiver of subsequent events. A nil state drops events
+// and dismisses any dialog that opens.
+func (r *tabRouter) set(state *tabState) {
+	r.mu.Lock()
+	r.cur = state
+	r.mu.Unlock()
+}
+
+func (r *tabRouter) current() *tabState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cur
+}
+
+// PromptResponse is the text prompt() dialogs are answered with when accepted,
+// so code consuming the return value of a payload's prompt() sees a known value.
+const PromptResponse = "dalfox"
+
+// acceptDialog accepts the open dialog, answering a prompt with PromptResponse
+func acceptDialog() *page.HandleJavaScriptDialogParams {
+	return page.HandleJavaScriptDialog(true).WithPromptText(PromptResponse)
+}
+
+// listenTab installs the event router on a tab
+func (m *Manager) listenTab(ctx context.Context) *tabRouter {
+	router := &tabRouter{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused, *fetch.EventAuthRequired:
+			m.handleFetchEvent(ctx, ev, router.current())
+			return
+		case *page.EventJavascriptDialogOpening:
+			state := router.current()
+			if state == nil {
+				go func() {
+					_ = chromedp.Run(ctx, page.HandleJavaScriptDialog(false))
+				}()
+				return
+			}
+			// the first dialog is handed to the validation, which keeps it open for
+			// the screenshot; later ones are only counted and accepted right away.
+			// Dialogs raised by the site itself are accepted without being counted.
+			if state.matches(e) && state.addDialog(e) == 1 {
+				select {
+				case state.dialogCh <- e:
+				default:
+				}
+				return
+			}
+			go func() {
+				_ = chromedp.Run(ctx, acceptDialog())
+			}()
+			return
+		case *network.EventResponseReceived:
+			if state := router.current(); state != nil {
+				state.setDocument(e)
+			}
+			return
+		case *network.EventRequestWillBeSent:
+			// also counted by the network tracker below
+			if state := router.current(); state != nil {
+				state.setRequest(e)
+			}
+		case *runtime.EventConsoleAPICalled:
+			if isTraceEvent(e) {
+				if state := router.current(); state != nil {
+					state.setTrace(formatStackTrace(e.StackTrace))
+				}
+				return
+			}
+		}
+		if state := router.current(); state != nil && !state.network.handle(ev) {
+			state.console.handle(ev)
+		}
+	})
+	return router
+}
+
+// resetTab detaches the previous validation from the tab: late events are dropped,
+// an open dialog is dismissed and the page is replaced with about:blank so its
+// timers cannot fire into the next validation.
+func (m *Manager) resetTab(ctx context.Context, router *tabRouter) error {
+	router.set(nil)
+	_ = chromedp.Run(ctx, page.HandleJavaScriptDialog(false))
+	return m.runWithTimeout(ctx, chromedp.Navigate("about:blank"))
+}
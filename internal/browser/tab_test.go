@@ -0,0 +1,184 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
+
+func TestTabState_dialogSummary(t *testing.T) {
+	s := newTabState()
+	for _, msg := range []string{"welcome", "1", "1", "document.domain"} {
+		s.addDialog(&page.EventJavascriptDialogOpening{Type: page.DialogTypeAlert, Message: msg})
+	}
+	count, evidence := s.dialogSummary()
+	if count != 4 {
+		t.Errorf("dialogSummary() count = %d, want 4", count)
+	}
+	if want := "welcome | 1 | document.domain"; evidence != want {
+		t.Errorf("dialogSummary() evidence = %q, want %q", evidence, want)
+	}
+}
+
+func TestTabState_matches(t *testing.T) {
+	s := newTabState()
+	site := &page.EventJavascriptDialogOpening{Type: page.DialogTypeAlert, Message: "welcome"}
+	ours := &page.EventJavascriptDialogOpening{Type: page.DialogTypeAlert, Message: "xss dalfox0123456789ab"}
+	if !s.matches(site) {
+		t.Errorf("matches() without expectation should accept every dialog")
+	}
+	s.expectDialog("dalfox0123456789ab")
+	if s.matches(site) {
+		t.Errorf("matches() accepted a site dialog in strict mode")
+	}
+	if !s.matches(ours) {
+		t.Errorf("matches() rejected the payload dialog in strict mode")
+	}
+}
+
+func TestTabState_setDocument(t *testing.T) {
+	s := newTabState()
+	s.setDocument(&network.EventResponseReceived{
+		RequestID: "img",
+		Type:      network.ResourceTypeImage,
+		Response:  &network.Response{URL: "https://example.com/x.png", Status: 200},
+	})
+	if s.document() != nil {
+		t.Fatalf("setDocument() recorded a non-document response")
+	}
+	s.setDocument(&network.EventResponseReceived{
+		RequestID: "main",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			URL:     "https://example.com/?q=1",
+			Status:  200,
+			Headers: network.Headers{"Content-Type": "text/html"},
+		},
+	})
+	s.setDocument(&network.EventResponseReceived{
+		RequestID: "frame",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{URL: "https://example.com/frame", Status: 404},
+	})
+	doc := s.document()
+	if doc == nil || doc.RequestID != "main" || doc.Status != 200 {
+		t.Fatalf("document() = %+v, want the first document response", doc)
+	}
+	if got := doc.Headers["Content-Type"]; got != "text/html" {
+		t.Errorf("document() Content-Type = %q, want text/html", got)
+	}
+}
+
+func TestExecutionProof_RawResponse(t *testing.T) {
+	if got := (ExecutionProof{}).RawResponse(); got != "" {
+		t.Errorf("RawResponse() without a response = %q, want empty", got)
+	}
+	p := ExecutionProof{
+		ResponseStatus:  200,
+		ResponseHeaders: map[string]string{"X-B": "2", "Content-Type": "text/html"},
+		ResponseBody:    "<p>hi</p>",
+	}
+	want := "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nX-B: 2\r\n\r\n<p>hi</p>"
+	if got := p.RawResponse(); got != want {
+		t.Errorf("RawResponse() = %q, want %q", got, want)
+	}
+}
+
+func TestTabState_request(t *testing.T) {
+	s := newTabState()
+	if s.request() != nil {
+		t.Fatalf("request() before any request = %+v, want nil", s.request())
+	}
+	s.setRequest(&network.EventRequestWillBeSent{
+		Type:    network.ResourceTypeScript,
+		Request: &network.Request{Method: "GET", URL: "https://example.com/app.js"},
+	})
+	s.setRequest(&network.EventRequestWillBeSent{
+		Type:    network.ResourceTypeDocument,
+		Request: &network.Request{Method: "GET", URL: "https://example.com/old"},
+	})
+	// the redirect target replaces the first hop
+	s.setRequest(&network.EventRequestWillBeSent{
+		Type: network.ResourceTypeDocument,
+		Request: &network.Request{
+			Method:  "GET",
+			URL:     "https://example.com/?q=1",
+			Headers: network.Headers{"User-Agent": "test", "Content-Type": "text/plain"},
+		},
+	})
+	s.setDocument(&network.EventResponseReceived{
+		Type:     network.ResourceTypeDocument,
+		Response: &network.Response{URL: "https://example.com/?q=1", Status: 200},
+	})
+	s.setRequest(&network.EventRequestWillBeSent{
+		Type:    network.ResourceTypeDocument,
+		Request: &network.Request{Method: "GET", URL: "https://example.com/frame"},
+	})
+
+	req := s.request()
+	if req == nil || req.URL != "https://example.com/?q=1" || req.Method != "GET" {
+		t.Fatalf("request() = %+v, want the main document request", req)
+	}
+
+	s.setPOST(&postRequest{Body: "q=<svg>"})
+	s.takePOST()
+	req = s.request()
+	if req.Method != "POST" || req.Body != "q=<svg>" || req.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Errorf("request() with POST override = %+v", req)
+	}
+	if req.Headers["User-Agent"] != "test" {
+		t.Errorf("request() dropped headers of the original request")
+	}
+}
+
+func TestExecutionProof_RawRequest(t *testing.T) {
+	if got := (ExecutionProof{}).RawRequest(); got != "" {
+		t.Errorf("RawRequest() without a request = %q, want empty", got)
+	}
+	p := ExecutionProof{
+		RequestMethod:  "POST",
+		RequestURL:     "https://example.com:8443/search?q=1",
+		RequestHeaders: map[string]string{"User-Agent": "test", "Content-Type": "application/x-www-form-urlencoded"},
+		RequestBody:    "q=<svg>",
+	}
+	want := "POST /search?q=1 HTTP/1.1\r\nHost: example.com:8443\r\nContent-Type: application/x-www-form-urlencoded\r\nUser-Agent: test\r\n\r\nq=<svg>"
+	if got := p.RawRequest(); got != want {
+		t.Errorf("RawRequest() = %q, want %q", got, want)
+	}
+
+	var poc model.PoC
+	p.ApplyToPoC(&poc)
+	if poc.RawHTTPRequest != want {
+		t.Errorf("ApplyToPoC() RawHTTPRequest = %q, want %q", poc.RawHTTPRequest, want)
+	}
+}
+
+func TestExecutionProof_ApplyToPoCScreenshot(t *testing.T) {
+	var poc model.PoC
+	ExecutionProof{ScreenshotData: []byte("\xff\xd8jpeg")}.ApplyToPoC(&poc)
+	if want := "/9hqcGVn"; poc.ScreenshotBase64 != want {
+		t.Errorf("ScreenshotBase64 = %q, want %q", poc.ScreenshotBase64, want)
+	}
+
+	poc = model.PoC{}
+	ExecutionProof{}.ApplyToPoC(&poc)
+	if poc.ScreenshotBase64 != "" {
+		t.Errorf("ScreenshotBase64 without a screenshot = %q, want empty", poc.ScreenshotBase64)
+	}
+}
+
+func TestExecutionProof_ApplyToPoCEvidence(t *testing.T) {
+	var poc model.PoC
+	ExecutionProof{Evidence: "1"}.ApplyToPoC(&poc)
+	if poc.Evidence != "1" {
+		t.Errorf("Evidence = %q, want the proof evidence", poc.Evidence)
+	}
+
+	poc = model.PoC{Evidence: "reflected code"}
+	ExecutionProof{Evidence: "1"}.ApplyToPoC(&poc)
+	if poc.Evidence != "reflected code" {
+		t.Errorf("Evidence = %q, want the evidence set by the scan kept", poc.Evidence)
+	}
+}
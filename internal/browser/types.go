@@ -1,12 +1,29 @@
 package browser
 
-import "time"
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hahwul/dalfox/v2/pkg/model"
+)
 
 // BrowserSession represents a single browser session
 type BrowserSession struct {
 	ID        string
 	CreatedAt time.Time
 	Active    bool
+	LastUsed  time.Time
+
+	// ctx is a chromedp context bound to the session's own browser context, so
+	// cookies and connections survive between validations in the same session.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // BrowserConfig configuration for browser manager
@@ -15,8 +32,122 @@ type BrowserConfig struct {
 	DisableSandbox       bool   `json:"disable-sandbox"`
 	Timeout              int    `json:"timeout"`
 	WaitForAlertOnlyTime int    `json:"wait-for-alert-only-time"`
+	PostNavigateWaitMs   int    `json:"post-navigate-wait-ms"` // settle time after navigation before the dialog wait starts
+	NavRetries           int    `json:"nav-retries"`           // retries of a transiently failing navigation (default 0)
+	NavRetryBackoffMs    int    `json:"nav-retry-backoff-ms"`  // first retry delay, doubled per retry (default 500)
 	ChromiumBinaryPath   string `json:"chromium-binary-path"`
 	TakeScreenshots      bool   `json:"take-screenshots"`
+	SnapshotDir          string `json:"snapshot-dir"`          // root of <format>/ and html/ (default "snapshots")
+	SnapshotRetention    int    `json:"snapshot-retention"`    // hours; Initialize purges older evidence files (0 = keep all)
+	ScreenshotFormat     string `json:"screenshot-format"`     // jpg (default), png, webp, avif or auto (png or jpg by content)
+	ScreenshotQuality    int    `json:"screenshot-quality"`    // 1-100 (default 90), out of range values are clamped
+	KeepOriginalPNG      bool   `json:"keep-original-png"`     // also save the lossless capture under <snapshot-dir>/png/
+	CapturePageHTML      bool   `json:"capture-page-html"`     // save the rendered DOM under <snapshot-dir>/html/ on execution
+	ScreenshotSelector   string `json:"screenshot-selector"`   // capture only this element, full page if it does not match
+	MaxScreenshotWidth   int    `json:"max-screenshot-width"`  // downscale wider captures, keeping the aspect ratio (0 = no limit)
+	MaxScreenshotHeight  int    `json:"max-screenshot-height"` // downscale taller captures, e.g. of infinite-scroll pages (0 = no limit)
+	CaptureAnnotatedSVG  bool   `json:"capture-annotated-svg"` // save an SVG outlining where the payload landed under <snapshot-dir>/svg/
+	MaxTabs              int    `json:"max-tabs"`              // concurrent tabs on the shared browser (default 10)
+	MaxSessions          int    `json:"max-sessions"`          // pooled sessions (default 4)
+	SessionIdleTTL       int    `json:"session-idle-ttl"`      // seconds before an idle session is reaped (default 60)
+	DetectDOMExecution   bool   `json:"detect-dom-execution"`
+	WaitForNetworkIdle   bool   `json:"wait-for-network-idle"` // hold the dialog wait until no request ran for 500ms
+	StrictDialogMatch    bool   `json:"strict-dialog-match"`   // ignore dialogs that do not mention the payload canary
+	VerifyChromeOnInit   bool   `json:"verify-chrome-on-init"` // launch Chrome in Initialize and fail if it does not run
+	CaptureStackTrace    bool   `json:"capture-stack-trace"`   // record the JavaScript stack that raised the dialog
+	MaxEvidenceLen       int    `json:"max-evidence-len"`      // cap in bytes of Evidence and PageTitle (default 4096, -1 = no limit)
+
+	// ScreenshotToDiskDisabled keeps evidence in memory only, for read-only
+	// deployments: ScreenshotData and PageHTML are filled but no file is written,
+	// so the *Path fields of the proof stay empty and no annotated SVG is made.
+	ScreenshotToDiskDisabled bool `json:"screenshot-to-disk-disabled"`
+
+	// NavigationsPerSecond limits how often tabs navigate to the same host, so
+	// scanning one target with many payloads does not trip rate limits or a WAF.
+	// Navigations over the limit wait for their turn; 0 disables the limit.
+	NavigationsPerSecond float64 `json:"navigations-per-second,omitempty"`
+
+	// TotalPayloadTimeout caps a whole validation in seconds: waiting for a tab,
+	// navigation with its retries and the dialog window. Without it a slow page
+	// can take Timeout and then WaitForAlertOnlyTime on top; 0 disables the cap.
+	TotalPayloadTimeout int `json:"total-payload-timeout,omitempty"`
+
+	// ClickSelectors are clicked in order once the page loaded, e.g. a tab or a
+	// submit button, and WaitForSelector must then become visible before the
+	// dialog window starts, for sinks that only render after UI interaction.
+	// Both are bounded by Timeout; a selector that does not show up fails the
+	// validation unless a dialog was raised first.
+	ClickSelectors  []string `json:"click-selectors,omitempty"`
+	WaitForSelector string   `json:"wait-for-selector,omitempty"`
+
+	// PreActions run in the validation tab before every payload navigation, e.g.
+	// to accept a cookie banner or log in on the way to a sink the payload URL
+	// alone cannot reach. They share the tab's session, headers and cookies; no
+	// validation is attached meanwhile, so their dialogs are dismissed and never
+	// count as execution. A failing step fails the validation.
+	PreActions []Action `json:"pre-actions,omitempty"`
+
+	// ViewportWidth and ViewportHeight emulate a viewport of that size in CSS
+	// pixels, DeviceScaleFactor its pixel ratio (default 1), so sinks that only
+	// render on small screens are reached. DevicePreset emulates a device of
+	// chromedp's table by name, e.g. "iPhone 12" or "Pixel 5 landscape", with its
+	// user agent and touch support; the fields above then override its metrics.
+	ViewportWidth     int     `json:"viewport-width,omitempty"`
+	ViewportHeight    int     `json:"viewport-height,omitempty"`
+	DeviceScaleFactor float64 `json:"device-scale-factor,omitempty"`
+	DevicePreset      string  `json:"device-preset,omitempty"`
+
+	// Headers are sent with every request of the validation tab (e.g. Cookie, Authorization)
+	Headers map[string]string `json:"headers,omitempty"`
+	// UserAgent replaces the default "HeadlessChrome" user agent, which WAFs
+	// block on sight, in requests and navigator.userAgent. When UserAgents is
+	// set, its entries are used round-robin per validation instead.
+	UserAgent  string   `json:"user-agent,omitempty"`
+	UserAgents []string `json:"user-agents,omitempty"`
+	// Cookies are set in the browser before the validation navigation
+	Cookies []Cookie `json:"cookies,omitempty"`
+	// BlockResourceTypes aborts requests of these CDP resource types, e.g.
+	// ["Image", "Font", "Stylesheet", "Media"], which dialog detection does not
+	// need; pages load faster without them. Documents are always loaded.
+	BlockResourceTypes []string `json:"block-resource-types,omitempty"`
+	// BlockedDomains aborts every request, documents included, to these hosts
+	// and their subdomains, keeping analytics, ads and other out-of-scope third
+	// parties from being contacted. "*.example.com" is the same as "example.com".
+	BlockedDomains []string `json:"blocked-domains,omitempty"`
+
+	// ProxyServer routes browser traffic through an upstream proxy. Both HTTP
+	// ("http://127.0.0.1:8080") and SOCKS5 ("socks5://127.0.0.1:1080") schemes are
+	// accepted. ProxyBypassList is a Chrome bypass list such as "localhost;*.internal".
+	// ProxyUsername/ProxyPassword answer proxy auth challenges (HTTP proxies only,
+	// Chrome does not support SOCKS authentication).
+	ProxyServer     string `json:"proxy-server,omitempty"`
+	ProxyBypassList string `json:"proxy-bypass-list,omitempty"`
+	ProxyUsername   string `json:"proxy-username,omitempty"`
+	ProxyPassword   string `json:"proxy-password,omitempty"`
+
+	// BasicAuthUser and BasicAuthPass answer HTTP authentication challenges of
+	// the validated site, so apps behind Basic auth are validated rather than
+	// their 401 page.
+	BasicAuthUser string `json:"basic-auth-user,omitempty"`
+	BasicAuthPass string `json:"basic-auth-pass,omitempty"`
+
+	// BeEFAPIURL is the REST API root VerifyBeEFHook polls for hooked browsers,
+	// e.g. "http://127.0.0.1:3000/api"; empty derives it from the hook URL.
+	// BeEFAPIToken is the API token shown by BeEF on startup.
+	BeEFAPIURL   string `json:"beef-api-url,omitempty"`
+	BeEFAPIToken string `json:"beef-api-token,omitempty"`
+}
+
+// Cookie is a cookie injected into the browser before navigation. A Domain such
+// as "example.com" also applies to its subdomains; an empty Domain scopes the
+// cookie to the host of the validated URL.
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	HTTPOnly bool   `json:"http-only,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
 }
 
 // ValidationResult contains the result of payload validation in browser
@@ -24,8 +155,48 @@ type ValidationResult struct {
 	IsVulnerable       bool             `json:"is-vulnerable"`
 	ExecutionDetected  bool             `json:"execution-detected"`
 	ExecutionProofs    []ExecutionProof `json:"execution-proofs"`
+	PageTitle          string           `json:"page-title,omitempty"`
 	Error              error            `json:"error"`
 	ValidationDuration time.Duration    `json:"validation-duration"`
+	NavRetries         int              `json:"nav-retries,omitempty"` // navigation attempts repeated after transient failures
+	DryRun             *DryRunInfo      `json:"dry-run,omitempty"`     // set by DryRunValidate only
+	Engine             string           `json:"engine,omitempty"`      // browser engine that confirmed execution, one of the Engine constants
+}
+
+// Browser engines a validation can run in. The Manager always uses Chromium;
+// Firefox and WebKit need a Playwright backend.
+const (
+	EngineChromium = "chromium"
+	EngineFirefox  = "firefox"
+	EngineWebKit   = "webkit"
+)
+
+// ParseEngine returns the Engine constant named by s, case-insensitively. An
+// empty s is EngineChromium. Other values fail with ErrInvalidEngine, so a typo
+// is not mistaken for a Playwright engine.
+func ParseEngine(s string) (string, error) {
+	switch engine := strings.ToLower(strings.TrimSpace(s)); engine {
+	case "":
+		return EngineChromium, nil
+	case EngineChromium, EngineFirefox, EngineWebKit:
+		return engine, nil
+	}
+	return "", fmt.Errorf("%w: %q, want %s, %s or %s", ErrInvalidEngine, s, EngineChromium, EngineFirefox, EngineWebKit)
+}
+
+// DryRunInfo describes what a validation would do, see Manager.DryRunValidate
+type DryRunInfo struct {
+	URL           string `json:"url"`
+	TargetSHA256  string `json:"target-sha256"`
+	PayloadSHA256 string `json:"payload-sha256"`
+	Canary        string `json:"canary"`
+	SnapshotName  string `json:"snapshot-name"` // evidence file name without extension
+	// paths the evidence would be written to on execution; HTMLPath and SVGPath
+	// are only set when the corresponding capture is enabled, none is set when
+	// ScreenshotToDiskDisabled is
+	ScreenshotPath string `json:"screenshot-path"`
+	HTMLPath       string `json:"html-path,omitempty"`
+	SVGPath        string `json:"svg-path,omitempty"`
 }
 
 // ExecutionProof contains proof of JavaScript execution
@@ -33,10 +204,113 @@ type ExecutionProof struct {
 	PayloadSHA256    string    `json:"payload-sha256"`
 	ExecutionType    string    `json:"execution-type"` // alert, confirm, prompt
 	ExecutedAt       time.Time `json:"executed-at"`
-	Evidence         string    `json:"evidence"`
+	Evidence         string    `json:"evidence"`     // distinct dialog messages joined by " | "
+	DialogCount      int       `json:"dialog-count"` // number of dialogs raised by the payload
 	PageURL          string    `json:"page-url"`
+	FinalURL         string    `json:"final-url,omitempty"`   // location of the executing page, differs from PageURL after redirects
+	FrameURL         string    `json:"frame-url,omitempty"`   // iframe that raised the dialog, empty for the page itself
+	ShadowHost       string    `json:"shadow-host,omitempty"` // shadow hosts around the sentinel call of a dom-change, e.g. "my-app >>> user-card#c1"
 	PageTitle        string    `json:"page-title"`
 	ExecutionContext string    `json:"execution-context"`
+	PromptDefault    string    `json:"prompt-default,omitempty"` // default value of a prompt() dialog, often the payload echoed back
 	ScreenshotPath   string    `json:"screenshot-path"`
-	ScreenshotData   []byte    `json:"screenshot-data"`
+	ScreenshotData   []byte    `json:"screenshot-data"` // raw image bytes, see ApplyToPoC
+	OriginalPNGPath  string    `json:"original-png-path,omitempty"`
+	PageHTML         string    `json:"page-html,omitempty"`
+	HTMLPath         string    `json:"html-path,omitempty"`
+	SVGPath          string    `json:"svg-path,omitempty"` // annotated SVG, see BrowserConfig.CaptureAnnotatedSVG
+	ConsoleLogs      []string  `json:"console-logs,omitempty"`
+	ConsoleErrors    []string  `json:"console-errors,omitempty"`
+	StackTrace       []string  `json:"stack-trace,omitempty"` // innermost frame first, see BrowserConfig.CaptureStackTrace
+
+	// HTTP request that loaded the executing document
+	RequestMethod  string            `json:"request-method,omitempty"`
+	RequestURL     string            `json:"request-url,omitempty"`
+	RequestHeaders map[string]string `json:"request-headers,omitempty"`
+	RequestBody    string            `json:"request-body,omitempty"`
+
+	// HTTP response that delivered the executing document
+	ResponseStatus  int               `json:"response-status,omitempty"`
+	ResponseHeaders map[string]string `json:"response-headers,omitempty"`
+	ResponseBody    string            `json:"response-body,omitempty"`
+}
+
+// ApplyToPoC copies the browser validation evidence into a scan PoC. Evidence,
+// e.g. the dialog message, fills PoC.Evidence unless the scan already set it.
+// ScreenshotData holds the raw image and is base64 encoded only here, for
+// PoC.ScreenshotBase64. Migration: it used to hold the base64 text itself, so
+// callers that decoded ScreenshotData must now use it as is.
+func (p ExecutionProof) ApplyToPoC(poc *model.PoC) {
+	poc.BrowserValidated = true
+	poc.ExecutionDetected = true
+	poc.ExecutionType = p.ExecutionType
+	poc.ExecutionContext = p.ExecutionContext
+	poc.ScreenshotPath = p.ScreenshotPath
+	if len(p.ScreenshotData) > 0 {
+		poc.ScreenshotBase64 = base64.StdEncoding.EncodeToString(p.ScreenshotData)
+	}
+	poc.JSConsoleLogs = p.ConsoleLogs
+	poc.JSConsoleErrors = p.ConsoleErrors
+	poc.ValidationTimestamp = p.ExecutedAt.Unix()
+	if poc.Evidence == "" {
+		poc.Evidence = p.Evidence
+	}
+	if poc.RawHTTPRequest == "" && p.RequestMethod != "" {
+		poc.RawHTTPRequest = p.RawRequest()
+	}
+	if poc.RawHTTPResponse == "" && p.ResponseStatus != 0 {
+		poc.RawHTTPResponse = p.RawResponse()
+	}
+}
+
+// RawRequest renders the captured document request as raw HTTP/1.1 text: the
+// request line, a Host header and the other headers in sorted order, then the
+// body. It returns an empty string when no request was captured.
+func (p ExecutionProof) RawRequest() string {
+	if p.RequestMethod == "" {
+		return ""
+	}
+	target, host := p.RequestURL, ""
+	if u, err := url.Parse(p.RequestURL); err == nil && u.Host != "" {
+		target, host = u.RequestURI(), u.Host
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", p.RequestMethod, target)
+	if host != "" {
+		fmt.Fprintf(&b, "Host: %s\r\n", host)
+	}
+	keys := make([]string, 0, len(p.RequestHeaders))
+	for k := range p.RequestHeaders {
+		if !strings.EqualFold(k, "Host") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, p.RequestHeaders[k])
+	}
+	b.WriteString("\r\n")
+	b.WriteString(p.RequestBody)
+	return b.String()
+}
+
+// RawResponse renders the captured document response as raw HTTP/1.1 text with
+// headers in sorted order. It returns an empty string when no response was captured.
+func (p ExecutionProof) RawResponse() string {
+	if p.ResponseStatus == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", p.ResponseStatus, http.StatusText(p.ResponseStatus))
+	keys := make([]string, 0, len(p.ResponseHeaders))
+	for k := range p.ResponseHeaders {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, p.ResponseHeaders[k])
+	}
+	b.WriteString("\r\n")
+	b.WriteString(p.ResponseBody)
+	return b.String()
 }
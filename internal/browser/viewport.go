@@ -0,0 +1,49 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/chromedp/device"
+)
+
+// devicePresets maps the lower-cased names of chromedp's device table ("iphone 12",
+// "pixel 5 landscape", ...) to their metrics
+var devicePresets = sync.OnceValue(func() map[string]device.Info {
+	presets := make(map[string]device.Info)
+	for d := device.Reset + 1; d <= device.MotoG4landscape; d++ {
+		info := d.Device()
+		presets[strings.ToLower(info.Name)] = info
+	}
+	return presets
+})
+
+// emulatedDevice returns the device a validation tab emulates, or nil to keep
+// Chrome's own window. DevicePreset provides the metrics, user agent and touch
+// support of a real device; ViewportWidth, ViewportHeight and DeviceScaleFactor
+// override its metrics, or describe a desktop viewport on their own.
+func emulatedDevice(cfg *BrowserConfig) (*device.Info, error) {
+	var d device.Info
+	if cfg.DevicePreset != "" {
+		preset, ok := devicePresets()[strings.ToLower(strings.TrimSpace(cfg.DevicePreset))]
+		if !ok {
+			return nil, fmt.Errorf("unknown device preset %q", cfg.DevicePreset)
+		}
+		d = preset
+	} else if cfg.ViewportWidth <= 0 || cfg.ViewportHeight <= 0 {
+		return nil, nil
+	} else {
+		d = device.Info{Name: "custom", Scale: 1}
+	}
+	if cfg.ViewportWidth > 0 {
+		d.Width = int64(cfg.ViewportWidth)
+	}
+	if cfg.ViewportHeight > 0 {
+		d.Height = int64(cfg.ViewportHeight)
+	}
+	if cfg.DeviceScaleFactor > 0 {
+		d.Scale = cfg.DeviceScaleFactor
+	}
+	return &d, nil
+}
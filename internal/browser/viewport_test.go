@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_emulatedDevice(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        BrowserConfig
+		wantNil    bool
+		wantErr    bool
+		wantWidth  int64
+		wantHeight int64
+		wantScale  float64
+		wantMobile bool
+	}{
+		{name: "none", wantNil: true},
+		{name: "width only", cfg: BrowserConfig{ViewportWidth: 800}, wantNil: true},
+		{name: "viewport", cfg: BrowserConfig{ViewportWidth: 800, ViewportHeight: 600}, wantWidth: 800, wantHeight: 600, wantScale: 1},
+		{name: "viewport scaled", cfg: BrowserConfig{ViewportWidth: 800, ViewportHeight: 600, DeviceScaleFactor: 2}, wantWidth: 800, wantHeight: 600, wantScale: 2},
+		{name: "preset", cfg: BrowserConfig{DevicePreset: "iPhone 12"}, wantWidth: 390, wantHeight: 844, wantScale: 3, wantMobile: true},
+		{name: "preset case insensitive", cfg: BrowserConfig{DevicePreset: " iphone 12 landscape"}, wantWidth: 844, wantHeight: 390, wantScale: 3, wantMobile: true},
+		{name: "preset overridden", cfg: BrowserConfig{DevicePreset: "iPhone 12", ViewportHeight: 500}, wantWidth: 390, wantHeight: 500, wantScale: 3, wantMobile: true},
+		{name: "unknown preset", cfg: BrowserConfig{DevicePreset: "Nokia 3310"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := emulatedDevice(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("emulatedDevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (d == nil) != tt.wantNil {
+				t.Fatalf("emulatedDevice() = %v, want nil %v", d, tt.wantNil)
+			}
+			if d == nil {
+				return
+			}
+			if d.Width != tt.wantWidth || d.Height != tt.wantHeight || d.Scale != tt.wantScale || d.Mobile != tt.wantMobile {
+				t.Errorf("emulatedDevice() = %+v", *d)
+			}
+		})
+	}
+}
+
+func TestManager_ValidatePayloadViewport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<script>alert(innerWidth + 'x' + devicePixelRatio)</script>")
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, BrowserConfig{DevicePreset: "iPhone 12"})
+	res := m.ValidatePayload("", srv.URL, "viewport-test", "html")
+	if res.Error != nil || len(res.ExecutionProofs) == 0 {
+		t.Fatalf("ValidatePayload() = %+v, want execution", res)
+	}
+	if got := res.ExecutionProofs[0].Evidence; got != "390x3" {
+		t.Errorf("viewport = %q, want %q", got, "390x3")
+	}
+}
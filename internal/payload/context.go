@@ -0,0 +1,85 @@
+package payload
+
+import "strings"
+
+// DetectContext reports where marker is reflected in reflectedHTML: CtxJS inside
+// a <script> block, CtxATTR inside a tag (attribute name or value) and CtxHTML
+// anywhere else, including when marker is not found. Only the first reflection
+// is considered. The scan is a light state machine rather than a full HTML
+// parser, so it also works on snippets cut out of a response.
+func DetectContext(reflectedHTML string, marker string) string {
+	idx := strings.Index(reflectedHTML, marker)
+	if marker == "" || idx < 0 {
+		return CtxHTML
+	}
+
+	const (
+		inText = iota
+		inTag
+		inComment
+		inScript
+	)
+	s := reflectedHTML[:idx]
+	state := inText
+	tagStart := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch state {
+		case inText:
+			if hasPrefixFold(s[i:], "<!--") {
+				state = inComment
+				i += len("<!--") - 1
+			} else if s[i] == '<' && i+1 < len(s) && isTagNameStart(s[i+1]) {
+				state, tagStart, quote = inTag, i, 0
+			}
+		case inTag:
+			switch {
+			case quote != 0:
+				if s[i] == quote {
+					quote = 0
+				}
+			case s[i] == '"' || s[i] == '\'':
+				quote = s[i]
+			case s[i] == '>':
+				state = inText
+				if isScriptStart(s[tagStart:]) {
+					state = inScript
+				}
+			}
+		case inComment:
+			if strings.HasPrefix(s[i:], "-->") {
+				state = inText
+				i += len("-->") - 1
+			}
+		case inScript:
+			if hasPrefixFold(s[i:], "</script") {
+				state, tagStart, quote = inTag, i, 0
+			}
+		}
+	}
+
+	switch state {
+	case inTag:
+		return CtxATTR
+	case inScript:
+		return CtxJS
+	default:
+		return CtxHTML
+	}
+}
+
+// isTagNameStart reports whether c can follow '<' in a start or end tag
+func isTagNameStart(c byte) bool {
+	return c == '/' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isScriptStart reports whether tag begins with a <script> start tag
+func isScriptStart(tag string) bool {
+	const open = "<script"
+	return hasPrefixFold(tag, open) && len(tag) > len(open) && strings.IndexByte(" \t\n\r\f/>", tag[len(open)]) >= 0
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring ASCII case
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
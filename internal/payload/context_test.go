@@ -0,0 +1,33 @@
+package payload
+
+import "testing"
+
+func TestDetectContext(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "tag body", body: `<div>hello Dalfox</div>`, want: CtxHTML},
+		{name: "double quoted attribute", body: `<input type="text" value="Dalfox">`, want: CtxATTR},
+		{name: "single quoted attribute", body: `<a href='/x?q=Dalfox'>x</a>`, want: CtxATTR},
+		{name: "unquoted attribute", body: `<img src=Dalfox>`, want: CtxATTR},
+		{name: "gt inside quoted value", body: `<a title="a>b" href="Dalfox">`, want: CtxATTR},
+		{name: "script block", body: `<script>var q = "Dalfox";</script>`, want: CtxJS},
+		{name: "script with attributes", body: `<SCRIPT type="text/javascript">q='Dalfox'</SCRIPT>`, want: CtxJS},
+		{name: "after script block", body: `<script>var a=1;</script><p>Dalfox</p>`, want: CtxHTML},
+		{name: "tag inside script string", body: `<script>var h = "<b>"; var q = "Dalfox";</script>`, want: CtxJS},
+		{name: "script tag attribute", body: `<script src="/js?v=Dalfox"></script>`, want: CtxATTR},
+		{name: "comment", body: `<!-- <a href="x --> Dalfox`, want: CtxHTML},
+		{name: "less than in text", body: `1 < 2 Dalfox`, want: CtxHTML},
+		{name: "similar tag name", body: `<scripts>Dalfox</scripts>`, want: CtxHTML},
+		{name: "not reflected", body: `<script>nothing</script>`, want: CtxHTML},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContext(tt.body, "Dalfox"); got != tt.want {
+				t.Errorf("DetectContext(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
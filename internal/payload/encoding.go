@@ -0,0 +1,128 @@
+package payload
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Encodings produced by EncodingVariants
+const (
+	EncodingURL        = "url"            // %XX for every byte but letters and digits
+	EncodingDoubleURL  = "double-url"     // EncodingURL applied twice, for filters decoding once
+	EncodingHTMLEntity = "html-entity"    // &#xXX; for every character but letters and digits
+	EncodingUnicode    = "unicode-escape" // JavaScript \uXXXX escapes, for string contexts
+)
+
+// EncodedPayload is a payload variant together with the encoding that made it
+type EncodedPayload struct {
+	Encoding string
+	Payload  string
+}
+
+// payloadEncoders are the encodings of EncodingVariants, in output order
+var payloadEncoders = []struct {
+	name   string
+	encode func(string) string
+}{
+	{EncodingURL, percentEncode},
+	{EncodingDoubleURL, func(s string) string { return strings.ReplaceAll(percentEncode(s), "%", "%25") }},
+	{EncodingHTMLEntity, htmlEntityEncode},
+	{EncodingUnicode, unicodeEscape},
+}
+
+// EncodingVariants returns the URL, double URL, HTML entity and unicode escape
+// encoded variants of p, for filters that inspect the raw text but let an
+// encoding through that the sink decodes. Variants identical to p, as for a
+// payload of letters and digits only, are left out.
+func EncodingVariants(p string) []EncodedPayload {
+	var out []EncodedPayload
+	for _, enc := range payloadEncoders {
+		if v := enc.encode(p); v != p {
+			out = append(out, EncodedPayload{Encoding: enc.name, Payload: v})
+		}
+	}
+	return out
+}
+
+// GenerateEncodingVariants is EncodingVariants without the encoding names.
+func GenerateEncodingVariants(p string) []string {
+	variants := EncodingVariants(p)
+	out := make([]string, len(variants))
+	for i, v := range variants {
+		out[i] = v.Payload
+	}
+	return out
+}
+
+// WithEncodingVariants returns merged with the encoding variants of every
+// payload added to its context, after the original payloads. Variants keep the
+// metadata of their payload, the Sink included, since the sink decodes them,
+// and carry the encoding in Encoding. Payloads that are variants already are
+// not encoded again.
+func WithEncodingVariants(merged map[string][]PayloadMeta) map[string][]PayloadMeta {
+	result := newMergedPayloads()
+	for ctx, list := range merged {
+		result.add(ctx, list...)
+	}
+	for ctx, list := range merged {
+		for _, p := range list {
+			if p.Encoding != "" {
+				continue
+			}
+			for _, v := range EncodingVariants(p.Payload) {
+				variant := p
+				variant.Payload = v.Payload
+				variant.Encoding = v.Encoding
+				result.add(ctx, variant)
+			}
+		}
+	}
+	return result.lists
+}
+
+// isAlnum reports whether c is an ASCII letter or digit
+func isAlnum(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if isAlnum(rune(s[i])) {
+			b.WriteByte(s[i])
+		} else {
+			fmt.Fprintf(&b, "%%%02X", s[i])
+		}
+	}
+	return b.String()
+}
+
+func htmlEntityEncode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if isAlnum(r) {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "&#x%X;", r)
+		}
+	}
+	return b.String()
+}
+
+// unicodeEscape escapes every character but letters and digits; characters
+// beyond the BMP become surrogate pairs, as JavaScript strings hold UTF-16
+func unicodeEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case isAlnum(r):
+			b.WriteRune(r)
+		case r > 0xFFFF:
+			r -= 0x10000
+			fmt.Fprintf(&b, "\\u%04X\\u%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+		default:
+			fmt.Fprintf(&b, "\\u%04X", r)
+		}
+	}
+	return b.String()
+}
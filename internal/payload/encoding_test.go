@@ -0,0 +1,56 @@
+package payload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodingVariants(t *testing.T) {
+	got := EncodingVariants(`<svg onload=alert("é")>`)
+	want := []EncodedPayload{
+		{EncodingURL, "%3Csvg%20onload%3Dalert%28%22%C3%A9%22%29%3E"},
+		{EncodingDoubleURL, "%253Csvg%2520onload%253Dalert%2528%2522%25C3%25A9%2522%2529%253E"},
+		{EncodingHTMLEntity, "&#x3C;svg&#x20;onload&#x3D;alert&#x28;&#x22;&#xE9;&#x22;&#x29;&#x3E;"},
+		{EncodingUnicode, `\u003Csvg\u0020onload\u003Dalert\u0028\u0022\u00E9\u0022\u0029\u003E`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodingVariants() = %q, want %q", got, want)
+	}
+
+	if got := unicodeEscape("😀"); got != `\uD83D\uDE00` {
+		t.Errorf("unicodeEscape() of a non-BMP rune = %q, want a surrogate pair", got)
+	}
+	if got := GenerateEncodingVariants("dalfox"); len(got) != 0 {
+		t.Errorf("GenerateEncodingVariants() of a plain word = %q, want none", got)
+	}
+	if got := GenerateEncodingVariants("'"); !reflect.DeepEqual(got, []string{"%27", "%2527", "&#x27;", `\u0027`}) {
+		t.Errorf("GenerateEncodingVariants() = %q", got)
+	}
+}
+
+func TestWithEncodingVariants(t *testing.T) {
+	merged := map[string][]PayloadMeta{
+		CtxHTML: {{Payload: "<b>", Severity: "High", Sink: SinkInnerHTML}, {Payload: "%3Cb%3E"}},
+		CtxJS:   {{Payload: "x"}},
+	}
+	got := WithEncodingVariants(merged)
+
+	var html []string
+	for _, p := range got[CtxHTML] {
+		html = append(html, p.Payload)
+	}
+	// the URL variant of "<b>" is listed already and kept once
+	want := []string{"<b>", "%3Cb%3E", "%253Cb%253E", "&#x3C;b&#x3E;", `\u003Cb\u003E`, "%25253Cb%25253E", "&#x25;3Cb&#x25;3E", `\u00253Cb\u00253E`}
+	if !reflect.DeepEqual(html, want) {
+		t.Errorf("HTML payloads = %q, want %q", html, want)
+	}
+	if v := got[CtxHTML][2]; v.Severity != "High" || v.Sink != SinkInnerHTML || v.Encoding != EncodingDoubleURL {
+		t.Errorf("variant metadata = %+v, want the original's", v)
+	}
+	if again := WithEncodingVariants(got); len(again[CtxHTML]) != len(got[CtxHTML]) {
+		t.Errorf("WithEncodingVariants() encoded variants again")
+	}
+	if len(got[CtxJS]) != 1 || len(merged[CtxHTML]) != 2 {
+		t.Errorf("WithEncodingVariants() changed plain payloads or its input")
+	}
+}
@@ -2,8 +2,13 @@ package payload
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
+	"hash/maphash"
 	"os"
+	"path/filepath"
 	"strings"
+	"unicode"
 )
 
 // PayloadContext names
@@ -12,73 +17,489 @@ const (
 	CtxATTR = "ATTR"
 	CtxJS   = "JS"
 	CtxANY  = "ANY"
+	CtxMXSS = "MXSS" // mutation XSS, rewritten into executable form by the HTML parser
 )
 
-// LoadMergedPayloads loads default payloads from the package and merges with user-provided file.
-// It returns a map of context -> payload list. Context keys: HTML, ATTR, JS, ANY.
-// Custom payload lines may be tagged with [HTML], [ATTR], [JS]. Untagged lines are treated as ANY.
-func LoadMergedPayloads(customPath string) (map[string][]string, error) {
-	result := map[string][]string{
-		CtxHTML: {},
-		CtxATTR: {},
-		CtxJS:   {},
-		CtxANY:  {},
+// tagExclude marks custom lines that remove a payload instead of adding one
+const tagExclude = "EXCLUDE"
+
+// PayloadMeta is a payload with the optional metadata of its definition. In
+// custom files metadata follows the payload after ";;", e.g.
+//
+//	[JS] alert(1) ;; cwe=CWE-79 sev=high
+//
+// Empty fields mean the scanner should use its own defaults. Sink is filled
+// with ClassifyPayload when a payload is loaded unless its definition names one
+// with sink=.
+type PayloadMeta struct {
+	Payload  string
+	CWE      string
+	Severity string
+	Sink     string
+	Encoding string // set on the variants of WithEncodingVariants
+}
+
+// TaggedPayload is a payload together with the context it is meant for.
+// Payloads streamed by StreamMergedPayloads carry a non-nil Err instead when a
+// custom file fails while being read; the other fields are then empty.
+type TaggedPayload struct {
+	Context string
+	PayloadMeta
+	Err error
+}
+
+// mergedPayloads collects payloads per context, dropping duplicates within a
+// context while keeping the order in which payloads were first seen.
+type mergedPayloads struct {
+	lists map[string][]PayloadMeta
+	seen  map[string]map[string]struct{}
+}
+
+func newMergedPayloads() *mergedPayloads {
+	m := &mergedPayloads{
+		lists: make(map[string][]PayloadMeta),
+		seen:  make(map[string]map[string]struct{}),
+	}
+	for _, ctx := range []string{CtxHTML, CtxATTR, CtxJS, CtxANY, CtxMXSS} {
+		m.lists[ctx] = []PayloadMeta{}
+		m.seen[ctx] = make(map[string]struct{})
 	}
+	return m
+}
 
-	// Load defaults from existing getters
+// add appends payloads to ctx. Trailing whitespace is trimmed; empty and already
+// present payloads are skipped, so the metadata of the first definition wins.
+func (m *mergedPayloads) add(ctx string, payloads ...PayloadMeta) {
+	for _, p := range payloads {
+		p.Payload = strings.TrimRightFunc(p.Payload, unicode.IsSpace)
+		if p.Payload == "" {
+			continue
+		}
+		if _, ok := m.seen[ctx][p.Payload]; ok {
+			continue
+		}
+		if p.Sink == "" {
+			p.Sink = ClassifyPayload(p.Payload)
+		}
+		m.seen[ctx][p.Payload] = struct{}{}
+		m.lists[ctx] = append(m.lists[ctx], p)
+	}
+}
+
+// remove drops every payload in excluded from all contexts
+func (m *mergedPayloads) remove(excluded map[string]struct{}) {
+	if len(excluded) == 0 {
+		return
+	}
+	for ctx, list := range m.lists {
+		kept := list[:0]
+		for _, p := range list {
+			if _, ok := excluded[p.Payload]; ok {
+				delete(m.seen[ctx], p.Payload)
+				continue
+			}
+			kept = append(kept, p)
+		}
+		m.lists[ctx] = kept
+	}
+}
+
+// defaultPayloads returns the built-in payload lists in merge order
+func defaultPayloads() []TaggedPayload {
 	htmlList, _ := GetHTMLPayloadWithSize()
 	attrList, _ := GetAttrPayloadWithSize()
 	jsList, _ := GetInJsPayloadWithSize()
 	commonList, _ := GetCommonPayloadWithSize()
+	mxssList, _ := GetMXSSPayloadWithSize()
 
-	result[CtxHTML] = append(result[CtxHTML], htmlList...)
-	result[CtxATTR] = append(result[CtxATTR], attrList...)
-	result[CtxJS] = append(result[CtxJS], jsList...)
-	result[CtxANY] = append(result[CtxANY], commonList...)
+	out := make([]TaggedPayload, 0, len(htmlList)+len(attrList)+len(jsList)+len(commonList)+len(mxssList))
+	for _, group := range []struct {
+		ctx  string
+		list []string
+	}{{CtxHTML, htmlList}, {CtxATTR, attrList}, {CtxJS, jsList}, {CtxANY, commonList}, {CtxMXSS, mxssList}} {
+		for _, p := range group.list {
+			out = append(out, TaggedPayload{Context: group.ctx, PayloadMeta: PayloadMeta{Payload: p}})
+		}
+	}
+	return out
+}
 
-	// If no custom file provided, return
-	if customPath == "" {
-		return result, nil
+// parseCustomLine splits a custom payload line into its context and payload.
+// Untagged lines belong to ANY; [EXCLUDE] lines report tagExclude. ok is false
+// for blank lines, comments and tags without a payload.
+func parseCustomLine(line string) (ctx string, meta PayloadMeta, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", PayloadMeta{}, false
+	}
+	// Detect tags
+	ctx = CtxANY
+	upper := strings.ToUpper(line)
+	for _, tag := range []string{tagExclude, CtxHTML, CtxATTR, CtxJS, CtxMXSS} {
+		if prefix := "[" + tag + "]"; strings.HasPrefix(upper, prefix) {
+			ctx = tag
+			line = strings.TrimSpace(line[len(prefix):])
+			break
+		}
 	}
+	meta = parsePayloadMeta(line)
+	return ctx, meta, meta.Payload != ""
+}
 
-	f, err := os.Open(customPath)
+// parsePayloadMeta splits an optional ";; key=value ..." suffix off a payload.
+// Recognized keys are cwe, sev (or severity) and sink. A suffix with any other content
+// is kept as part of the payload, since ";;" is valid JavaScript.
+func parsePayloadMeta(s string) PayloadMeta {
+	meta := PayloadMeta{Payload: s}
+	i := strings.LastIndex(s, ";;")
+	if i < 0 {
+		return meta
+	}
+	fields := strings.Fields(s[i+len(";;"):])
+	if len(fields) == 0 {
+		return meta
+	}
+	var parsed PayloadMeta
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found || value == "" {
+			return meta
+		}
+		switch strings.ToLower(key) {
+		case "cwe":
+			parsed.CWE = normalizeCWE(value)
+		case "sev", "severity":
+			parsed.Severity = normalizeSeverity(value)
+		case "sink":
+			parsed.Sink = value
+		default:
+			return meta
+		}
+	}
+	parsed.Payload = strings.TrimSpace(s[:i])
+	return parsed
+}
+
+// normalizeCWE maps "79", "cwe-79" and "CWE-79" to "CWE-79"
+func normalizeCWE(v string) string {
+	v = strings.ToUpper(v)
+	if !strings.HasPrefix(v, "CWE-") {
+		v = "CWE-" + v
+	}
+	return v
+}
+
+// severities are the values a PoC severity can take
+var severities = []string{"Critical", "High", "Medium", "Low", "Info"}
+
+// normalizeSeverity maps "high", "HIGH" and "High" to "High". Values outside
+// severities yield "", so the finding keeps its default severity.
+func normalizeSeverity(v string) string {
+	for _, sev := range severities {
+		if strings.EqualFold(v, sev) {
+			return sev
+		}
+	}
+	return ""
+}
+
+// readExcludes adds the payloads excluded by [EXCLUDE] lines of a custom file
+// to excluded
+func readExcludes(path string, excluded map[string]struct{}) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return result, err
+		return err
 	}
 	defer f.Close()
 
 	s := bufio.NewScanner(f)
 	for s.Scan() {
-		line := strings.TrimSpace(s.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if ctx, meta, ok := parseCustomLine(s.Text()); ok && ctx == tagExclude {
+			excluded[meta.Payload] = struct{}{}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadMergedPayloads is LoadMergedPayloadsMeta without the payload metadata.
+func LoadMergedPayloads(customPath string) (map[string][]string, error) {
+	merged, err := LoadMergedPayloadsMeta(customPath)
+	return PayloadStrings(merged), err
+}
+
+// PayloadStrings drops the metadata of merged payload lists
+func PayloadStrings(merged map[string][]PayloadMeta) map[string][]string {
+	out := make(map[string][]string, len(merged))
+	for ctx, list := range merged {
+		payloads := make([]string, len(list))
+		for i, p := range list {
+			payloads[i] = p.Payload
+		}
+		out[ctx] = payloads
+	}
+	return out
+}
+
+// LoadMergedPayloadsMeta loads default payloads from the package and merges with user-provided file.
+// It returns a map of context -> payload list. Context keys: HTML, ATTR, JS, ANY, MXSS.
+// Custom payload lines may be tagged with [HTML], [ATTR], [JS], [MXSS]. Untagged lines are treated as ANY.
+// Each list is deduplicated, keeping the first occurrence, so a custom payload that
+// repeats a default one is only tested once. Lines tagged [EXCLUDE] remove the
+// exactly matching payload from every context, e.g. a default that trips a WAF.
+// Payload metadata (see PayloadMeta) is parsed from custom lines; defaults have none.
+// customPath may also be a directory, see LoadMergedPayloadsMetaMulti.
+func LoadMergedPayloadsMeta(customPath string) (map[string][]PayloadMeta, error) {
+	// If no custom file provided, only the defaults are returned
+	if customPath == "" {
+		return LoadMergedPayloadsMetaMulti(nil)
+	}
+	return LoadMergedPayloadsMetaMulti([]string{customPath})
+}
+
+// LoadMergedPayloadsWithVars is LoadMergedPayloadsMetaWithVars without the payload metadata.
+func LoadMergedPayloadsWithVars(customPath string, vars map[string]string) (map[string][]string, error) {
+	merged, err := LoadMergedPayloadsMetaWithVars(customPath, vars)
+	return PayloadStrings(merged), err
+}
+
+// LoadMergedPayloadsMetaWithVars is LoadMergedPayloadsMeta with {KEY} tokens in
+// payloads replaced by vars[KEY], e.g. {CALLBACK} by the callback URL of a blind
+// XSS engagement, so payload files need no per-engagement edits. Tokens without
+// a value in vars are left as they are.
+func LoadMergedPayloadsMetaWithVars(customPath string, vars map[string]string) (map[string][]PayloadMeta, error) {
+	merged, err := LoadMergedPayloadsMeta(customPath)
+	if len(vars) == 0 {
+		return merged, err
+	}
+	return expandPayloadVars(merged, vars), err
+}
+
+// LoadMergedPayloadsLimited is LoadMergedPayloadsMetaLimited without the payload metadata.
+func LoadMergedPayloadsLimited(customPath string, maxPerContext int) (map[string][]string, error) {
+	merged, err := LoadMergedPayloadsMetaLimited(customPath, maxPerContext)
+	return PayloadStrings(merged), err
+}
+
+// LoadMergedPayloadsMetaLimited is LoadMergedPayloadsMeta keeping at most
+// maxPerContext payloads per context, for quick scans with large custom lists.
+// See LimitPayloads for which payloads are kept.
+func LoadMergedPayloadsMetaLimited(customPath string, maxPerContext int) (map[string][]PayloadMeta, error) {
+	merged, err := LoadMergedPayloadsMeta(customPath)
+	limited, _ := LimitPayloads(merged, maxPerContext)
+	return limited, err
+}
+
+// LimitPayloads keeps the first maxPerContext payloads of each context and
+// returns how many were dropped in total. Merged lists are deduplicated and
+// start with the curated defaults, followed by custom payloads in file order,
+// so the selection is reproducible and favours the defaults. A maxPerContext of
+// zero or less keeps everything.
+func LimitPayloads(merged map[string][]PayloadMeta, maxPerContext int) (map[string][]PayloadMeta, int) {
+	if maxPerContext <= 0 {
+		return merged, 0
+	}
+	dropped := 0
+	out := make(map[string][]PayloadMeta, len(merged))
+	for ctx, list := range merged {
+		if len(list) > maxPerContext {
+			dropped += len(list) - maxPerContext
+			list = list[:maxPerContext:maxPerContext]
+		}
+		out[ctx] = list
+	}
+	return out, dropped
+}
+
+// expandPayloadVars substitutes {KEY} tokens and deduplicates again, since two
+// templates may expand to the same payload.
+func expandPayloadVars(merged map[string][]PayloadMeta, vars map[string]string) map[string][]PayloadMeta {
+	pairs := make([]string, 0, 2*len(vars))
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	r := strings.NewReplacer(pairs...)
+
+	result := newMergedPayloads()
+	for ctx, list := range merged {
+		for _, p := range list {
+			p.Payload = r.Replace(p.Payload)
+			result.add(ctx, p)
+		}
+	}
+	return result.lists
+}
+
+// LoadMergedPayloadsMulti is LoadMergedPayloadsMetaMulti without the payload metadata.
+func LoadMergedPayloadsMulti(paths []string) (map[string][]string, error) {
+	merged, err := LoadMergedPayloadsMetaMulti(paths)
+	return PayloadStrings(merged), err
+}
+
+// LoadMergedPayloadsMetaMulti is LoadMergedPayloadsMeta for several custom files,
+// merged in the given order. A directory contributes every *.txt file inside it
+// in name order. [EXCLUDE] lines apply to the payloads of all files. Files that
+// cannot be read are skipped; the returned error then joins one error per failed
+// file, each naming the file, while the payloads of the other files are still
+// returned.
+func LoadMergedPayloadsMetaMulti(paths []string) (map[string][]PayloadMeta, error) {
+	result := newMergedPayloads()
+
+	// Load defaults from existing getters
+	for _, tp := range defaultPayloads() {
+		result.add(tp.Context, tp.PayloadMeta)
+	}
+
+	files, errs := expandPayloadPaths(paths)
+	excluded := make(map[string]struct{})
+	for _, path := range files {
+		if err := readCustomFile(path, result, excluded); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	result.remove(excluded)
+
+	return result.lists, errors.Join(errs...)
+}
+
+// expandPayloadPaths replaces directories in paths by the *.txt files they contain
+func expandPayloadPaths(paths []string) ([]string, []error) {
+	var files []string
+	var errs []error
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		// Detect tags
-		upper := strings.ToUpper(line)
-		if strings.HasPrefix(upper, "[HTML]") {
-			payload := strings.TrimSpace(line[len("[HTML]"):])
-			if payload != "" {
-				result[CtxHTML] = append(result[CtxHTML], payload)
-			}
+		if !fi.IsDir() {
+			files = append(files, path)
 			continue
 		}
-		if strings.HasPrefix(upper, "[ATTR]") {
-			payload := strings.TrimSpace(line[len("[ATTR]"):])
-			if payload != "" {
-				result[CtxATTR] = append(result[CtxATTR], payload)
-			}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		if strings.HasPrefix(upper, "[JS]") {
-			payload := strings.TrimSpace(line[len("[JS]"):])
-			if payload != "" {
-				result[CtxJS] = append(result[CtxJS], payload)
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".txt") {
+				files = append(files, filepath.Join(path, e.Name()))
 			}
-			continue
 		}
-		// default: ANY
-		result[CtxANY] = append(result[CtxANY], line)
 	}
+	return files, errs
+}
+
+// readCustomFile adds the payloads of a custom file to result and collects its
+// [EXCLUDE] lines into excluded.
+func readCustomFile(path string, result *mergedPayloads, excluded map[string]struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		ctx, meta, ok := parseCustomLine(s.Text())
+		switch {
+		case !ok:
+		case ctx == tagExclude:
+			excluded[meta.Payload] = struct{}{}
+		default:
+			result.add(ctx, meta)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
+
+// StreamMergedPayloads yields the same payloads as LoadMergedPayloadsMeta, in the same
+// order, without building the lists in memory: defaults first, then the custom
+// file line by line. Only the [EXCLUDE] set and a 64-bit hash per emitted payload
+// are kept for exclusion and deduplication. customPath may also be a directory,
+// see StreamMergedPayloadsMulti.
+func StreamMergedPayloads(customPath string) (<-chan TaggedPayload, error) {
+	if customPath == "" {
+		return StreamMergedPayloadsMulti(nil)
+	}
+	return StreamMergedPayloadsMulti([]string{customPath})
+}
+
+// StreamMergedPayloadsMulti is StreamMergedPayloads for several custom files,
+// read in the order LoadMergedPayloadsMetaMulti reads them. Errors expanding the
+// paths or pre-reading the [EXCLUDE] lines, which may appear anywhere in a file,
+// are returned directly. A file that fails later on ends its part of the stream
+// with a TaggedPayload carrying the error, and the next file is read. The channel
+// is closed when all payloads were sent and must be drained by the caller.
+func StreamMergedPayloadsMulti(paths []string) (<-chan TaggedPayload, error) {
+	files, errs := expandPayloadPaths(paths)
+	excluded := make(map[string]struct{})
+	for _, path := range files {
+		if err := readExcludes(path, excluded); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan TaggedPayload, 64)
+	go func() {
+		defer close(ch)
+		seed := maphash.MakeSeed()
+		seen := make(map[uint64]struct{})
+		emit := func(ctx string, meta PayloadMeta) {
+			meta.Payload = strings.TrimRightFunc(meta.Payload, unicode.IsSpace)
+			if meta.Payload == "" {
+				return
+			}
+			if _, ok := excluded[meta.Payload]; ok {
+				return
+			}
+			key := maphash.String(seed, ctx+"\x00"+meta.Payload)
+			if _, ok := seen[key]; ok {
+				return
+			}
+			seen[key] = struct{}{}
+			if meta.Sink == "" {
+				meta.Sink = ClassifyPayload(meta.Payload)
+			}
+			ch <- TaggedPayload{Context: ctx, PayloadMeta: meta}
+		}
+
+		for _, tp := range defaultPayloads() {
+			emit(tp.Context, tp.PayloadMeta)
+		}
+		for _, path := range files {
+			if err := streamCustomFile(path, emit); err != nil {
+				ch <- TaggedPayload{Err: err}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// streamCustomFile passes the payload lines of a custom file to emit
+func streamCustomFile(path string, emit func(ctx string, meta PayloadMeta)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	return result, s.Err()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if ctx, meta, ok := parseCustomLine(s.Text()); ok && ctx != tagExclude {
+			emit(ctx, meta)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
 }
@@ -0,0 +1,408 @@
+package payload
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writePayloadFile writes content to a temporary custom payload file
+func writePayloadFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "payloads.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write payload file: %v", err)
+	}
+	return path
+}
+
+// count returns how often p occurs in list
+func count(list []string, p string) int {
+	n := 0
+	for _, v := range list {
+		if v == p {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoadMergedPayloads_Dedup(t *testing.T) {
+	htmlDefaults, _ := GetHTMLPayloadWithSize()
+	dup := htmlDefaults[0]
+	path := writePayloadFile(t, "[HTML] "+dup+"\n"+
+		"[HTML] <custom-a>  \n"+
+		"[html] <custom-a>\n"+
+		"<any-a>\n"+
+		"<any-a>\t\n"+
+		"[JS] <any-a>\n")
+
+	merged, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloads() error = %v", err)
+	}
+	if got := count(merged[CtxHTML], dup); got != 1 {
+		t.Errorf("default payload repeated in custom file appears %d times, want 1", got)
+	}
+	if got := count(merged[CtxHTML], "<custom-a>"); got != 1 {
+		t.Errorf("repeated custom payload appears %d times, want 1", got)
+	}
+	if got := count(merged[CtxANY], "<any-a>"); got != 1 {
+		t.Errorf("untagged repeated payload appears %d times, want 1", got)
+	}
+	// dedup is per context, the same payload may be listed for another context
+	if got := count(merged[CtxJS], "<any-a>"); got != 1 {
+		t.Errorf("payload in a second context appears %d times, want 1", got)
+	}
+
+	// first-seen order: defaults first, custom payloads after them
+	html := merged[CtxHTML]
+	if html[0] != dup || html[len(html)-1] != "<custom-a>" {
+		t.Errorf("unexpected order: first = %q, last = %q", html[0], html[len(html)-1])
+	}
+}
+
+func TestLoadMergedPayloads_MXSS(t *testing.T) {
+	path := writePayloadFile(t, "[MXSS] <custom-mxss>\n[mxss] <custom-mxss>\n")
+	merged, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloads() error = %v", err)
+	}
+	defaults, size := GetMXSSPayloadWithSize()
+	if got := merged[CtxMXSS]; len(got) != size+1 || got[0] != defaults[0] || got[size] != "<custom-mxss>" {
+		t.Errorf("MXSS payloads = %q, want the defaults then <custom-mxss>", got)
+	}
+	if count(merged[CtxANY], "<custom-mxss>") != 0 {
+		t.Errorf("[MXSS] payload also listed under ANY")
+	}
+}
+
+func TestLoadMergedPayloads_MissingFile(t *testing.T) {
+	merged, err := LoadMergedPayloads(filepath.Join(t.TempDir(), "missing.txt"))
+	if err == nil {
+		t.Errorf("LoadMergedPayloads() with a missing file should fail")
+	}
+	if len(merged[CtxHTML]) == 0 {
+		t.Errorf("defaults should still be returned when the custom file is missing")
+	}
+}
+
+func TestLoadMergedPayloads_Exclude(t *testing.T) {
+	htmlDefaults, _ := GetHTMLPayloadWithSize()
+	noisy := htmlDefaults[0]
+	path := writePayloadFile(t, "[EXCLUDE] "+noisy+"\n"+
+		"[HTML] <keep-me>\n"+
+		"[JS] <drop-me>\n"+
+		"<drop-me>\n"+
+		"[exclude]   <drop-me>  \n"+
+		"[EXCLUDE] <drop-me\n")
+
+	merged, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloads() error = %v", err)
+	}
+	for ctx, list := range merged {
+		if count(list, noisy) != 0 {
+			t.Errorf("excluded default payload still present in %s", ctx)
+		}
+		if count(list, "<drop-me>") != 0 {
+			t.Errorf("excluded custom payload still present in %s", ctx)
+		}
+	}
+	if count(merged[CtxHTML], "<keep-me>") != 1 {
+		t.Errorf("non-excluded custom payload was removed")
+	}
+	if len(merged[CtxHTML]) != len(htmlDefaults) {
+		t.Errorf("HTML payloads = %d, want %d (one default excluded, one custom added)", len(merged[CtxHTML]), len(htmlDefaults))
+	}
+}
+
+// drainPayloads collects a payload stream per context, failing on streamed errors
+func drainPayloads(t *testing.T, ch <-chan TaggedPayload) map[string][]string {
+	t.Helper()
+	got := map[string][]string{CtxHTML: {}, CtxATTR: {}, CtxJS: {}, CtxANY: {}, CtxMXSS: {}}
+	for tp := range ch {
+		if tp.Err != nil {
+			t.Errorf("streamed error: %v", tp.Err)
+			continue
+		}
+		got[tp.Context] = append(got[tp.Context], tp.Payload)
+	}
+	return got
+}
+
+func TestStreamMergedPayloads(t *testing.T) {
+	htmlDefaults, _ := GetHTMLPayloadWithSize()
+	path := writePayloadFile(t, "[HTML] <custom-a>\n"+
+		"[HTML] "+htmlDefaults[0]+"\n"+
+		"<any-a>\n"+
+		"<any-a>\n"+
+		"[EXCLUDE] "+htmlDefaults[1]+"\n")
+
+	want, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloads() error = %v", err)
+	}
+	ch, err := StreamMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("StreamMergedPayloads() error = %v", err)
+	}
+	got := drainPayloads(t, ch)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamMergedPayloads() differs from LoadMergedPayloads()")
+		for ctx := range want {
+			if len(got[ctx]) != len(want[ctx]) {
+				t.Errorf("%s: streamed %d payloads, loaded %d", ctx, len(got[ctx]), len(want[ctx]))
+			}
+		}
+	}
+
+	if _, err := StreamMergedPayloads(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("StreamMergedPayloads() with a missing file should fail")
+	}
+}
+
+func Test_parsePayloadMeta(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PayloadMeta
+	}{
+		{"alert(1)", PayloadMeta{Payload: "alert(1)"}},
+		{"alert(1) ;; cwe=CWE-79 sev=high", PayloadMeta{Payload: "alert(1)", CWE: "CWE-79", Severity: "High"}},
+		{"<svg onload=alert(1)>;;severity=MEDIUM cwe=83", PayloadMeta{Payload: "<svg onload=alert(1)>", CWE: "CWE-83", Severity: "Medium"}},
+		{"x ;; sev=INFO", PayloadMeta{Payload: "x", Severity: "Info"}},
+		// severities outside Critical/High/Medium/Low/Info are dropped
+		{"x ;; sev=urgent cwe=79", PayloadMeta{Payload: "x", CWE: "CWE-79"}},
+		{"x ;; sev=éleve", PayloadMeta{Payload: "x"}},
+		{"a;;b;; cwe=cwe-80", PayloadMeta{Payload: "a;;b", CWE: "CWE-80"}},
+		// JavaScript that merely contains ";;" is left untouched
+		{"for(;;){alert(1)}", PayloadMeta{Payload: "for(;;){alert(1)}"}},
+		{"x;; foo=bar", PayloadMeta{Payload: "x;; foo=bar"}},
+		{"x;;", PayloadMeta{Payload: "x;;"}},
+		{"<b>x</b> ;; sink=innerHTML", PayloadMeta{Payload: "<b>x</b>", Sink: "innerHTML"}},
+	}
+	for _, tt := range tests {
+		if got := parsePayloadMeta(tt.in); got != tt.want {
+			t.Errorf("parsePayloadMeta(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadMergedPayloadsMeta(t *testing.T) {
+	path := writePayloadFile(t, "[JS] alert(1337) ;; cwe=CWE-79 sev=high\n"+
+		"[JS] alert(1337) ;; sev=low\n"+
+		"<plain-any>\n")
+	merged, err := LoadMergedPayloadsMeta(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloadsMeta() error = %v", err)
+	}
+	js := merged[CtxJS]
+	last := js[len(js)-1]
+	if want := (PayloadMeta{Payload: "alert(1337)", CWE: "CWE-79", Severity: "High", Sink: SinkJSExpression}); last != want {
+		t.Errorf("custom JS payload = %+v, want %+v", last, want)
+	}
+	if count(PayloadStrings(merged)[CtxJS], "alert(1337)") != 1 {
+		t.Errorf("payload defined twice with different metadata should be kept once")
+	}
+
+	plain, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadMergedPayloads() error = %v", err)
+	}
+	if anyList := plain[CtxANY]; anyList[len(anyList)-1] != "<plain-any>" {
+		t.Errorf("LoadMergedPayloads() last ANY payload = %q, want %q", anyList[len(anyList)-1], "<plain-any>")
+	}
+}
+
+func TestLoadMergedPayloadsMulti(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":     "[HTML] <from-a>\n<shared>\n",
+		"b.TXT":     "[HTML] <from-b>\n<shared>\n[EXCLUDE] <from-single>\n",
+		"notes.md":  "<from-notes>\n",
+		"sub/c.txt": "<from-sub>\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	single := writePayloadFile(t, "[HTML] <from-single>\n[HTML] <from-a>\n")
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+
+	merged, err := LoadMergedPayloadsMulti([]string{single, dir, missing})
+	if err == nil || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("LoadMergedPayloadsMulti() error = %v, want one naming missing.txt", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadMergedPayloadsMulti() error should wrap os.ErrNotExist")
+	}
+
+	html := merged[CtxHTML]
+	if got := html[len(html)-2:]; !reflect.DeepEqual(got, []string{"<from-a>", "<from-b>"}) {
+		t.Errorf("tail of HTML payloads = %q, want files merged in order", got)
+	}
+	if count(html, "<from-a>") != 1 {
+		t.Errorf("payload from two files should be kept once")
+	}
+	if count(html, "<from-single>") != 0 {
+		t.Errorf("[EXCLUDE] in one file should apply to the others")
+	}
+	if count(merged[CtxANY], "<shared>") != 1 {
+		t.Errorf("ANY payload shared by files should be kept once")
+	}
+	for _, p := range []string{"<from-notes>", "<from-sub>"} {
+		if count(merged[CtxANY], p) != 0 {
+			t.Errorf("%s should not be loaded from the directory", p)
+		}
+	}
+}
+
+func TestStreamMergedPayloadsMulti(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":    "[HTML] <from-a>\n<shared>\n",
+		"b.txt":    "[HTML] <from-b>\n<shared>\n[EXCLUDE] <from-single>\n",
+		"notes.md": "<from-notes>\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	single := writePayloadFile(t, "[HTML] <from-single>\n[HTML] <from-a>\n")
+
+	want, err := LoadMergedPayloadsMulti([]string{single, dir})
+	if err != nil {
+		t.Fatalf("LoadMergedPayloadsMulti() error = %v", err)
+	}
+	ch, err := StreamMergedPayloadsMulti([]string{single, dir})
+	if err != nil {
+		t.Fatalf("StreamMergedPayloadsMulti() error = %v", err)
+	}
+	if got := drainPayloads(t, ch); !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamMergedPayloadsMulti() differs from LoadMergedPayloadsMulti()")
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		ch, err := StreamMergedPayloads(dir)
+		if err != nil {
+			t.Fatalf("StreamMergedPayloads() error = %v", err)
+		}
+		got := drainPayloads(t, ch)
+		if count(got[CtxHTML], "<from-b>") != 1 || count(got[CtxANY], "<from-notes>") != 0 {
+			t.Errorf("StreamMergedPayloads(dir) should stream the *.txt files of dir")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.txt")
+		_, err := StreamMergedPayloadsMulti([]string{single, missing})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("StreamMergedPayloadsMulti() error = %v, want os.ErrNotExist", err)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		path := writePayloadFile(t, "<ok>\n")
+		ch, err := StreamMergedPayloadsMulti([]string{path, single})
+		if err != nil {
+			t.Fatalf("StreamMergedPayloadsMulti() error = %v", err)
+		}
+		// the stream is still sending defaults, so the file is read after this
+		// change; a line longer than the scanner buffer fails the read
+		long := strings.Repeat("a", bufio.MaxScanTokenSize+1)
+		if err := os.WriteFile(path, []byte(long+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var errs []error
+		var payloads []string
+		for tp := range ch {
+			if tp.Err != nil {
+				errs = append(errs, tp.Err)
+				continue
+			}
+			payloads = append(payloads, tp.Payload)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], bufio.ErrTooLong) || !strings.Contains(errs[0].Error(), path) {
+			t.Errorf("streamed errors = %v, want one bufio.ErrTooLong naming the file", errs)
+		}
+		if count(payloads, "<from-single>") != 1 {
+			t.Errorf("files after the failed one should still be streamed")
+		}
+	})
+}
+
+func TestLoadMergedPayloadsWithVars(t *testing.T) {
+	path := writePayloadFile(t, "[HTML] <script src=\"{CALLBACK}/x.js\"></script>\n"+
+		"[HTML] <script src=\"https://cb.example/x.js\"></script>\n"+
+		"<img src={CALLBACK}/{ID}>\n"+
+		"<i>{UNKNOWN}</i>\n")
+	merged, err := LoadMergedPayloadsWithVars(path, map[string]string{"CALLBACK": "https://cb.example", "ID": "42"})
+	if err != nil {
+		t.Fatalf("LoadMergedPayloadsWithVars() error = %v", err)
+	}
+	if got := count(merged[CtxHTML], `<script src="https://cb.example/x.js"></script>`); got != 1 {
+		t.Errorf("expanded payload appears %d times, want 1 after dedup", got)
+	}
+	if count(merged[CtxANY], "<img src=https://cb.example/42>") != 1 {
+		t.Errorf("multiple tokens in one payload were not all replaced")
+	}
+	if count(merged[CtxANY], "<i>{UNKNOWN}</i>") != 1 {
+		t.Errorf("token without a value should be kept")
+	}
+
+	plain, _ := LoadMergedPayloads(path)
+	if withoutVars, _ := LoadMergedPayloadsWithVars(path, nil); !reflect.DeepEqual(withoutVars, plain) {
+		t.Errorf("LoadMergedPayloadsWithVars() without vars differs from LoadMergedPayloads()")
+	}
+}
+
+func TestLoadMergedPayloadsLimited(t *testing.T) {
+	path := writePayloadFile(t, "[JS] custom1\n[JS] custom2\n<u>any</u>\n")
+	full, err := LoadMergedPayloads(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		maxPerContext int
+	}{
+		{"cap", 3},
+		{"larger than any list", 1 << 20},
+		{"no limit", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMergedPayloadsLimited(path, tt.maxPerContext)
+			if err != nil {
+				t.Fatalf("LoadMergedPayloadsLimited() error = %v", err)
+			}
+			for ctx, list := range full {
+				want := list
+				if tt.maxPerContext > 0 && len(want) > tt.maxPerContext {
+					want = want[:tt.maxPerContext]
+				}
+				if !reflect.DeepEqual(got[ctx], want) {
+					t.Errorf("%s: got %d payloads, want the first %d", ctx, len(got[ctx]), len(want))
+				}
+			}
+		})
+	}
+
+	merged := map[string][]PayloadMeta{CtxHTML: {{Payload: "a"}, {Payload: "b"}, {Payload: "c"}}, CtxJS: {{Payload: "d"}}}
+	limited, dropped := LimitPayloads(merged, 1)
+	if dropped != 2 || len(limited[CtxHTML]) != 1 || len(limited[CtxJS]) != 1 {
+		t.Errorf("LimitPayloads() = %v, %d dropped, want one payload per context and 2 dropped", limited, dropped)
+	}
+	if limited[CtxHTML] = append(limited[CtxHTML], PayloadMeta{Payload: "x"}); merged[CtxHTML][1].Payload != "b" {
+		t.Errorf("appending to a limited list overwrote its input")
+	}
+}
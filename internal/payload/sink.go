@@ -0,0 +1,40 @@
+package payload
+
+import "regexp"
+
+// Sink classes reported by ClassifyPayload
+const (
+	SinkURLScheme      = "url-scheme"      // javascript:, data: or vbscript: URL
+	SinkAttributeEvent = "attribute-event" // on* event handler attribute
+	SinkScriptTag      = "script-tag"      // <script> element
+	SinkEval           = "eval"            // string evaluated as code
+	SinkInnerHTML      = "innerHTML"       // other markup parsed as HTML
+	SinkJSExpression   = "js-expression"   // bare JavaScript for a script context
+	SinkOther          = "other"
+)
+
+// sinkPatterns classify payloads by the sink they most likely exercise. The
+// first matching pattern wins, so the more specific sinks come first: an
+// <img onerror=eval(..)> runs through its event handler before eval.
+var sinkPatterns = []struct {
+	sink string
+	re   *regexp.Regexp
+}{
+	{SinkURLScheme, regexp.MustCompile(`(?i)(^|[\s"'=(])(javascript|vbscript|data)\s*:`)},
+	{SinkAttributeEvent, regexp.MustCompile(`(?i)[\s"'/]on[a-z]+\s*=`)},
+	{SinkScriptTag, regexp.MustCompile(`(?i)<script[\s>/]`)},
+	{SinkEval, regexp.MustCompile(`(?i)\b(eval|setTimeout|setInterval|Function|execScript)\s*[(\x60]`)},
+	{SinkInnerHTML, regexp.MustCompile(`<[a-zA-Z!/]`)},
+	{SinkJSExpression, regexp.MustCompile(`[\w$\]]\s*[(\x60]`)},
+}
+
+// ClassifyPayload returns the sink class of payload, one of the Sink constants,
+// inferred from its text with sinkPatterns. Payloads matching none are SinkOther.
+func ClassifyPayload(payload string) string {
+	for _, p := range sinkPatterns {
+		if p.re.MatchString(payload) {
+			return p.sink
+		}
+	}
+	return SinkOther
+}
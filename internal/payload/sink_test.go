@@ -0,0 +1,33 @@
+package payload
+
+import "testing"
+
+func TestClassifyPayload(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{"javascript:alert(1)", SinkURLScheme},
+		{`<a href="JavaScript:alert(1)">x</a>`, SinkURLScheme},
+		{"<iframe src=data:text/html,<script>alert(1)</script>>", SinkURLScheme},
+		{"<svg onload=alert(1)>", SinkAttributeEvent},
+		{"<img/src/onerror=eval(atob('YWxlcnQoMSk='))>", SinkAttributeEvent},
+		{`" autofocus onfocus="alert(1)`, SinkAttributeEvent},
+		{"<script>alert(1)</script>", SinkScriptTag},
+		{"<ScRiPt src=//x.test></script>", SinkScriptTag},
+		{"';eval('al'+'ert(1)');//", SinkEval},
+		{"setTimeout`alert\\x281\\x29`", SinkEval},
+		{"<math><mtext><table><mglyph><style><img>", SinkInnerHTML},
+		{"</title><b>x</b>", SinkInnerHTML},
+		{"';alert(1);//", SinkJSExpression},
+		{"${alert(1)}", SinkJSExpression},
+		{"dalfox", SinkOther},
+		// a single on* word is not an attribute
+		{"onion=1", SinkOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyPayload(tt.payload); got != tt.want {
+			t.Errorf("ClassifyPayload(%q) = %q, want %q", tt.payload, got, tt.want)
+		}
+	}
+}
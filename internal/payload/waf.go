@@ -0,0 +1,121 @@
+package payload
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// wafBypassTable is the curated table of SelectForWAF. Each rule names the WAF,
+// the substrings identifying it in a detected WAF name, regular expressions of
+// payloads known to bypass it in order of preference, and of payloads it is
+// known to block.
+//
+//go:embed data/waf_bypass.json
+var wafBypassTable []byte
+
+// wafRule is a compiled rule of wafBypassTable
+type wafRule struct {
+	WAF    string   `json:"waf"`
+	Match  []string `json:"match"`
+	Prefer []string `json:"prefer"`
+	Avoid  []string `json:"avoid"`
+
+	prefer []*regexp.Regexp
+	avoid  []*regexp.Regexp
+}
+
+// wafRules is wafBypassTable parsed and compiled; a malformed table is a build
+// mistake, caught by the package tests
+var wafRules = mustParseWAFRules(wafBypassTable)
+
+func mustParseWAFRules(table []byte) []wafRule {
+	var rules []wafRule
+	if err := json.Unmarshal(table, &rules); err != nil {
+		panic("payload: invalid WAF bypass table: " + err.Error())
+	}
+	for i := range rules {
+		for _, p := range rules[i].Prefer {
+			rules[i].prefer = append(rules[i].prefer, regexp.MustCompile(p))
+		}
+		for _, p := range rules[i].Avoid {
+			rules[i].avoid = append(rules[i].avoid, regexp.MustCompile(p))
+		}
+	}
+	return rules
+}
+
+// wafRuleFor returns the rule whose match strings occur in wafName, ignoring
+// case, or nil for a WAF without one
+func wafRuleFor(wafName string) *wafRule {
+	name := strings.ToLower(wafName)
+	for i := range wafRules {
+		for _, m := range wafRules[i].Match {
+			if strings.Contains(name, m) {
+				return &wafRules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// SelectForWAF is SelectForWAFMeta for payload lists without metadata.
+func SelectForWAF(payloads map[string][]string, wafName string) map[string][]string {
+	meta := make(map[string][]PayloadMeta, len(payloads))
+	for ctx, list := range payloads {
+		for _, p := range list {
+			meta[ctx] = append(meta[ctx], PayloadMeta{Payload: p})
+		}
+	}
+	return PayloadStrings(SelectForWAFMeta(meta, wafName))
+}
+
+// SelectForWAFMeta tailors context payload lists to the WAF named wafName, as
+// detected by the scanner (e.g. "CloudFlare Web Application Firewall
+// (CloudFlare)"). For Cloudflare, Akamai and ModSecurity, payloads the WAF is
+// known to block are dropped and those known to bypass it move to the front,
+// the others keeping their order behind them. For any other WAF, or none, the
+// lists are returned as they are. payloads is not modified.
+func SelectForWAFMeta(payloads map[string][]PayloadMeta, wafName string) map[string][]PayloadMeta {
+	rule := wafRuleFor(wafName)
+	out := make(map[string][]PayloadMeta, len(payloads))
+	for ctx, list := range payloads {
+		if rule == nil {
+			out[ctx] = append([]PayloadMeta(nil), list...)
+			continue
+		}
+		selected := []PayloadMeta{}
+		for _, p := range list {
+			if !matchesAny(rule.avoid, p.Payload) {
+				selected = append(selected, p)
+			}
+		}
+		sort.SliceStable(selected, func(i, j int) bool {
+			return rule.rank(selected[i].Payload) < rule.rank(selected[j].Payload)
+		})
+		out[ctx] = selected
+	}
+	return out
+}
+
+// rank is the index of the first prefer expression matching payload, or the
+// number of expressions when none does
+func (r *wafRule) rank(payload string) int {
+	for i, re := range r.prefer {
+		if re.MatchString(payload) {
+			return i
+		}
+	}
+	return len(r.prefer)
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
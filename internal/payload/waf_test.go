@@ -0,0 +1,59 @@
+package payload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectForWAF(t *testing.T) {
+	payloads := map[string][]string{
+		CtxHTML: {"<script>alert(1)</script>", "<img src=x onerror=alert(1)>", "<svg onload=confirm(1)>", "<b onpointerover=alert(1)>"},
+		CtxJS:   {"';alert(document.cookie)//", "'-alert(1)-'"},
+	}
+	tests := []struct {
+		name    string
+		wafName string
+		want    map[string][]string
+	}{
+		{
+			name:    "cloudflare",
+			wafName: "CloudFlare Web Application Firewall (CloudFlare)",
+			want: map[string][]string{
+				CtxHTML: {"<svg onload=confirm(1)>", "<b onpointerover=alert(1)>", "<img src=x onerror=alert(1)>"},
+				CtxJS:   {"'-alert(1)-'"},
+			},
+		},
+		{
+			name:    "modsecurity",
+			wafName: "ModSecurity: Open Source Web Application Firewall (Trustwave)",
+			want: map[string][]string{
+				CtxHTML: {"<img src=x onerror=alert(1)>", "<svg onload=confirm(1)>", "<b onpointerover=alert(1)>"},
+				CtxJS:   {"'-alert(1)-'"},
+			},
+		},
+		{name: "unknown WAF", wafName: "Safe3 Web Application Firewall", want: payloads},
+		{name: "no WAF", wafName: "", want: payloads},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectForWAF(payloads, tt.wafName); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectForWAF() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if len(payloads[CtxHTML]) != 4 {
+		t.Errorf("SelectForWAF() modified its input")
+	}
+}
+
+func TestWAFBypassTable(t *testing.T) {
+	for _, waf := range []string{"Cloudflare", "Akamai", "ModSecurity"} {
+		rule := wafRuleFor(waf)
+		if rule == nil || rule.WAF != waf {
+			t.Errorf("wafRuleFor(%q) = %v, want its rule", waf, rule)
+		}
+	}
+	if rule := wafRuleFor("KONA Security Solutions (Akamai Technologies)"); rule == nil || rule.WAF != "Akamai" {
+		t.Errorf("wafRuleFor() does not match the name checkWAF reports for Akamai")
+	}
+}
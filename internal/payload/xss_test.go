@@ -263,6 +263,18 @@ func TestGetInJsPayload(t *testing.T) {
 	}
 }
 
+func TestGetMXSSPayloadWithSize(t *testing.T) {
+	payloads, size := GetMXSSPayloadWithSize()
+	if len(payloads) != size {
+		t.Errorf("Expected size %d, but got %d", len(payloads), size)
+	}
+	for _, p := range payloads {
+		if !strings.Contains(p, "DALFOX_ALERT_VALUE") || !strings.Contains(p, "class=dalfox") {
+			t.Errorf("mXSS payload %q lacks the alert value or the dalfox class", p)
+		}
+	}
+}
+
 func TestGetDOMXSSPayload(t *testing.T) {
 	payloads := GetDOMXSSPayload()
 	if len(payloads) == 0 {
@@ -345,3 +357,25 @@ func TestSplitLines_xss(t *testing.T) {
 		})
 	}
 }
+
+func TestPayloadFiles(t *testing.T) {
+	entries, err := payloadFiles.ReadDir("data/payloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		list := payloadList(e.Name())
+		if len(list) == 0 {
+			t.Errorf("%s has no payloads", e.Name())
+		}
+		for _, p := range list {
+			if strings.HasPrefix(p, "#") || strings.TrimSpace(p) == "" {
+				t.Errorf("%s: comment or blank line %q returned as a payload", e.Name(), p)
+			}
+		}
+	}
+	// lines are taken verbatim
+	if got := payloadList("attr.txt")[0]; !strings.HasSuffix(got, "class=dalfox ") {
+		t.Errorf("payloadList() trimmed %q", got)
+	}
+}
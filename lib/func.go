@@ -147,6 +147,9 @@ func Initialize(target Target, options Options) model.Options {
 	if options.MaxCPU != 0 {
 		newOptions.MaxCPU = options.MaxCPU
 	}
+	if options.MaxPayloadsPerContext != 0 {
+		newOptions.MaxPayloadsPerContext = options.MaxPayloadsPerContext
+	}
 	if options.ServerPort != 0 {
 		newOptions.ServerPort = options.ServerPort
 	}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// csvHeader is the header row written by ToCSV
+var csvHeader = []string{"Type", "Method", "Param", "Payload", "Severity", "CWE", "ExecutionType", "ScreenshotPath"}
+
+// ToCSV writes the PoCs of r to w as CSV, a header row followed by one row per
+// PoC. Fields containing commas, quotes or newlines are quoted per RFC 4180.
+func (r *Result) ToCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, poc := range r.PoCs {
+		row := []string{poc.Type, poc.Method, poc.Param, poc.Payload, poc.Severity, poc.CWE, poc.ExecutionType, poc.ScreenshotPath}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResult_ToCSV(t *testing.T) {
+	r := &Result{PoCs: []PoC{
+		{Type: "V", Method: "GET", Param: "q", Payload: `<img src=x onerror="alert(1,2)">`, Severity: "High", CWE: "CWE-79", ExecutionType: "alert", ScreenshotPath: "/tmp/a.jpg"},
+		{Type: "R", Method: "POST", Param: "name", Payload: "line1\nline2", Severity: "Medium", CWE: "CWE-83"},
+	}}
+
+	var sb strings.Builder
+	if err := r.ToCSV(&sb); err != nil {
+		t.Fatalf("ToCSV() error = %v", err)
+	}
+	if !strings.Contains(sb.String(), `"<img src=x onerror=""alert(1,2)"">"`) {
+		t.Errorf("payload with commas and quotes is not quoted:\n%s", sb.String())
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("ToCSV() output is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		{"Type", "Method", "Param", "Payload", "Severity", "CWE", "ExecutionType", "ScreenshotPath"},
+		{"V", "GET", "q", `<img src=x onerror="alert(1,2)">`, "High", "CWE-79", "alert", "/tmp/a.jpg"},
+		{"R", "POST", "name", "line1\nline2", "Medium", "CWE-83", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ToCSV() rows = %q, want %q", rows, want)
+	}
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// htmlReport holds the data of the template executed by ToHTML
+type htmlReport struct {
+	StartTime time.Time
+	Duration  time.Duration
+	Confirmed int
+	PoCs      []htmlPoC
+}
+
+// htmlPoC is a PoC with its severity badge and screenshot prepared for the report
+type htmlPoC struct {
+	PoC
+	SeverityClass string
+	Screenshot    template.URL // data: URL of the screenshot, empty without one
+}
+
+// htmlSeverityClasses maps SeverityScore to the CSS class of the badge
+var htmlSeverityClasses = [...]string{"unknown", "info", "low", "medium", "high", "critical"}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Dalfox report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: .5em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+code, pre { font-family: ui-monospace, monospace; font-size: .9em; word-break: break-all; white-space: pre-wrap; }
+.badge { border-radius: 3px; color: #fff; padding: .1em .5em; font-size: .85em; }
+.critical { background: #7b1fa2; } .high { background: #d32f2f; } .medium { background: #f57c00; }
+.low { background: #fbc02d; color: #222; } .info { background: #1976d2; } .unknown { background: #757575; }
+img { max-width: 480px; border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>Dalfox report</h1>
+<p>{{len .PoCs}} findings, {{.Confirmed}} confirmed{{if not .StartTime.IsZero}}, scan started {{.StartTime.Format "2006-01-02 15:04:05 MST"}}{{end}}{{if .Duration}}, took {{.Duration}}{{end}}.</p>
+<table>
+<tr><th>Severity</th><th>Type</th><th>Method</th><th>Param</th><th>Payload</th><th>Details</th></tr>
+{{range .PoCs}}<tr>
+<td><span class="badge {{.SeverityClass}}">{{or .Severity "unknown"}}</span></td>
+<td>{{.Type}}{{if .InjectType}} / {{.InjectType}}{{end}}</td>
+<td>{{.Method}}</td>
+<td><code>{{.Param}}</code></td>
+<td><code>{{.Payload}}</code></td>
+<td>
+<a href="{{.Data}}">{{.Data}}</a>
+{{if .Evidence}}<p>Evidence: <code>{{.Evidence}}</code></p>{{end}}
+{{if .ExecutionDetected}}<p>Executed in a browser ({{.ExecutionType}}{{if .ExecutionContext}}, {{.ExecutionContext}}{{end}})</p>{{end}}
+{{if .Screenshot}}<p><img src="{{.Screenshot}}" alt="screenshot of the executed payload"></p>{{end}}
+{{if .RawHTTPRequest}}<details><summary>Request</summary><pre>{{.RawHTTPRequest}}</pre></details>{{end}}
+{{if .RawHTTPResponse}}<details><summary>Response</summary><pre>{{.RawHTTPResponse}}</pre></details>{{end}}
+</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ToHTML renders r as a self-contained HTML page for sharing with people who do
+// not read JSON: a table of the PoCs with severity badges, the screenshots of
+// browser-validated findings embedded as data: URLs, and the raw request and
+// response in collapsible sections. All PoC content is escaped.
+func (r *Result) ToHTML() ([]byte, error) {
+	report := htmlReport{StartTime: r.StartTime, Duration: r.Duration, PoCs: make([]htmlPoC, 0, len(r.PoCs))}
+	for _, poc := range r.PoCs {
+		if poc.IsConfirmed() {
+			report.Confirmed++
+		}
+		report.PoCs = append(report.PoCs, htmlPoC{
+			PoC:           poc,
+			SeverityClass: htmlSeverityClasses[poc.SeverityScore()],
+			Screenshot:    screenshotDataURL(poc.ScreenshotBase64),
+		})
+	}
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// screenshotDataURL returns a data: URL of a base64 encoded screenshot, or ""
+// when it is empty, not base64 or not an image. The check is what makes the URL
+// safe to mark as trusted for the template.
+func screenshotDataURL(b64 string) template.URL {
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil || len(raw) == 0 {
+		return ""
+	}
+	mime := http.DetectContentType(raw)
+	if len(raw) > 12 && string(raw[4:12]) == "ftypavif" {
+		mime = "image/avif" // not sniffed by net/http
+	}
+	if !strings.HasPrefix(mime, "image/") {
+		return ""
+	}
+	return template.URL("data:" + mime + ";base64," + b64)
+}
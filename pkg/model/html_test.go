@@ -0,0 +1,55 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResult_ToHTML(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	screenshot := base64.StdEncoding.EncodeToString(img.Bytes())
+	r := &Result{
+		StartTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Duration:  90 * time.Second,
+		PoCs: []PoC{
+			{
+				Type: "V", Method: "GET", Param: "q", Payload: "<script>alert(1)</script>", Severity: "High",
+				Data: "http://t.test/?q=<script>", BrowserValidated: true, ExecutionDetected: true, ExecutionType: "alert",
+				ScreenshotBase64: screenshot, RawHTTPRequest: "GET /?q=x HTTP/1.1", RawHTTPResponse: "HTTP/1.1 200 OK",
+			},
+			{Type: "R", Param: "id", Payload: "x", ScreenshotBase64: "not base64!", Data: "javascript:alert(1)"},
+		},
+	}
+
+	out, err := r.ToHTML()
+	if err != nil {
+		t.Fatalf("ToHTML() error = %v", err)
+	}
+	html := string(out)
+	for _, want := range []string{
+		"2 findings, 1 confirmed",
+		`<span class="badge high">High</span>`,
+		`<span class="badge unknown">unknown</span>`,
+		`<img src="data:image/png;base64,` + screenshot + `"`,
+		"<details><summary>Request</summary><pre>GET /?q=x HTTP/1.1</pre></details>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("ToHTML() output lacks %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>") || strings.Contains(html, `href="javascript:`) {
+		t.Errorf("ToHTML() output contains unescaped payload content")
+	}
+	if strings.Count(html, "<img") != 1 {
+		t.Errorf("ToHTML() embedded an invalid screenshot")
+	}
+}
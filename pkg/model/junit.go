@@ -0,0 +1,132 @@
+package model
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// junitClassName is the classname of every testcase written by ToJUnit
+const junitClassName = "dalfox.xss"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// IsConfirmed reports whether the PoC is a verified XSS rather than a mere
+// reflection, either by the scanner's own check or by browser execution.
+func (p PoC) IsConfirmed() bool {
+	return p.Type == "V" || p.ExecutionDetected
+}
+
+// ToJUnit renders r as JUnit XML so CI pipelines can fail a build on findings.
+// Every confirmed PoC becomes a failing testcase named after its parameter and
+// payload, with the evidence and screenshot path in the failure message. Every
+// analysed parameter without a confirmed PoC becomes a passing testcase.
+// Unconfirmed reflections are left out.
+func (r *Result) ToJUnit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:  "dalfox",
+		Time:  fmt.Sprintf("%.3f", r.Duration.Seconds()),
+		Cases: []junitTestCase{},
+	}
+	if !r.StartTime.IsZero() {
+		suite.Timestamp = r.StartTime.Format("2006-01-02T15:04:05")
+	}
+
+	vulnerable := make(map[string]bool)
+	for _, poc := range r.PoCs {
+		if !poc.IsConfirmed() {
+			continue
+		}
+		vulnerable[poc.Param] = true
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      poc.Param + " " + poc.Payload,
+			ClassName: junitClassName,
+			Failure: &junitFailure{
+				Message: junitFailureMessage(poc),
+				Type:    sarifRuleID(poc.CWE),
+				Text:    junitFailureText(poc),
+			},
+		})
+		suite.Failures++
+	}
+
+	seen := make(map[string]bool)
+	for _, param := range r.Params {
+		if vulnerable[param.Name] || seen[param.Name] {
+			continue
+		}
+		seen[param.Name] = true
+		suite.Cases = append(suite.Cases, junitTestCase{Name: param.Name, ClassName: junitClassName})
+	}
+	suite.Tests = len(suite.Cases)
+
+	out, err := xml.MarshalIndent(junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// junitFailureMessage summarizes why a confirmed PoC fails its testcase
+func junitFailureMessage(poc PoC) string {
+	parts := []string{"XSS confirmed"}
+	if poc.Evidence != "" {
+		parts = append(parts, "evidence: "+poc.Evidence)
+	}
+	if poc.ScreenshotPath != "" {
+		parts = append(parts, "screenshot: "+poc.ScreenshotPath)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// junitFailureText is the detailed failure body of a confirmed PoC
+func junitFailureText(poc PoC) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "URL: %s\n", poc.Data)
+	fmt.Fprintf(&sb, "Method: %s\n", poc.Method)
+	fmt.Fprintf(&sb, "Param: %s\n", poc.Param)
+	fmt.Fprintf(&sb, "Payload: %s\n", poc.Payload)
+	fmt.Fprintf(&sb, "Severity: %s\n", poc.Severity)
+	if poc.ExecutionType != "" {
+		fmt.Fprintf(&sb, "Execution: %s\n", poc.ExecutionType)
+	}
+	if poc.Evidence != "" {
+		fmt.Fprintf(&sb, "Evidence: %s\n", poc.Evidence)
+	}
+	if poc.ScreenshotPath != "" {
+		fmt.Fprintf(&sb, "Screenshot: %s\n", poc.ScreenshotPath)
+	}
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResult_ToJUnit(t *testing.T) {
+	r := &Result{
+		Duration: 1500 * time.Millisecond,
+		PoCs: []PoC{
+			{Type: "V", Param: "q", Payload: "<svg onload=alert(1)>", CWE: "CWE-79", Evidence: "12 line: <svg onload=alert(1)>", ScreenshotPath: "/tmp/q.jpg"},
+			{Type: "R", Param: "name", Payload: `"><b>`},
+			{Type: "R", Param: "id", Payload: "x", ExecutionDetected: true},
+		},
+		Params: []ParamResult{{Name: "q"}, {Name: "name"}, {Name: "page"}, {Name: "page"}},
+	}
+
+	out, err := r.ToJUnit()
+	if err != nil {
+		t.Fatalf("ToJUnit() error = %v", err)
+	}
+	if !strings.HasPrefix(string(out), xml.Header) {
+		t.Errorf("ToJUnit() output lacks the XML header")
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(out, &suites); err != nil {
+		t.Fatalf("ToJUnit() produced invalid XML: %v", err)
+	}
+	if suites.Tests != 4 || suites.Failures != 2 || suites.Time != "1.500" {
+		t.Errorf("testsuites = {tests %d, failures %d, time %s}, want {4, 2, 1.500}", suites.Tests, suites.Failures, suites.Time)
+	}
+
+	tests := []struct {
+		name   string
+		failed bool
+	}{
+		{"q <svg onload=alert(1)>", true},
+		{"id x", true},
+		{"name", false},
+		{"page", false},
+	}
+	cases := suites.Suites[0].Cases
+	if len(cases) != len(tests) {
+		t.Fatalf("testcases = %d, want %d", len(cases), len(tests))
+	}
+	for i, tt := range tests {
+		if cases[i].Name != tt.name || (cases[i].Failure != nil) != tt.failed {
+			t.Errorf("testcase %d = {%q failed=%v}, want {%q failed=%v}", i, cases[i].Name, cases[i].Failure != nil, tt.name, tt.failed)
+		}
+	}
+	if msg := cases[0].Failure.Message; !strings.Contains(msg, "evidence: 12 line") || !strings.Contains(msg, "screenshot: /tmp/q.jpg") {
+		t.Errorf("failure message = %q, want evidence and screenshot", msg)
+	}
+}
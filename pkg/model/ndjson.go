@@ -0,0 +1,43 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// NDJSONWriter streams PoCs as JSON lines (one object per line), so long scans
+// can be consumed live by log shippers or jq. It is safe for concurrent use.
+type NDJSONWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewNDJSONWriter returns a writer appending PoCs to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+// Append writes poc as a single newline-terminated line. When the underlying
+// writer buffers (bufio.Writer, http.ResponseWriter, ...) it is flushed, so the
+// line is visible downstream as soon as Append returns.
+func (n *NDJSONWriter) Append(poc PoC) error {
+	line, err := json.Marshal(poc)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(line); err != nil {
+		return err
+	}
+	switch f := n.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNDJSONWriter_Append(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	w := NewNDJSONWriter(bw)
+
+	if err := w.Append(PoC{Type: "V", Param: "q", Payload: "<svg\nonload=alert(1)>"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	// the bufio.Writer must have been flushed
+	if !strings.HasSuffix(buf.String(), "}\n") {
+		t.Fatalf("Append() output = %q, want a flushed newline-terminated line", buf.String())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = w.Append(PoC{Type: "R", Param: "p"})
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 21 {
+		t.Fatalf("got %d lines, want 21", len(lines))
+	}
+	var first PoC
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.Payload != "<svg\nonload=alert(1)>" {
+		t.Errorf("first line = %q, err = %v", lines[0], err)
+	}
+	for i, line := range lines[1:] {
+		var poc PoC
+		if err := json.Unmarshal([]byte(line), &poc); err != nil {
+			t.Errorf("line %d is not a JSON object: %v", i+1, err)
+		}
+	}
+}
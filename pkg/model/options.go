@@ -30,6 +30,7 @@ type Options struct {
 	// Feature Options
 	BlindURL                  string `json:"blind,omitempty"`
 	CustomPayloadFile         string `json:"custom-payload-file,omitempty"`
+	MaxPayloadsPerContext     int    `json:"max-payloads-per-context,omitempty"`
 	CustomBlindXSSPayloadFile string `json:"custom-blind-xss-payload-file,omitempty"`
 	CustomAlertValue          string `json:"custom-alert-value,omitempty"`
 	CustomAlertType           string `json:"custom-alert-type,omitempty"`
@@ -43,6 +44,15 @@ type Options struct {
 	UseHeadless               bool   `json:"use-headless,omitempty"`
 	UseDeepDXSS               bool   `json:"use-deepdxss,omitempty"`
 	HeadlessTimeout           int    `json:"headless-timeout,omitempty"`
+	HeadlessConcurrency       int    `json:"headless-concurrency,omitempty"`
+	ChromiumPath              string `json:"chromium-path,omitempty"`
+	PuppeteerNodePath         string `json:"puppeteer-node-path,omitempty"`    // node binary for the Puppeteer and Playwright verifiers, default "node"
+	PuppeteerScriptPath       string `json:"puppeteer-script-path,omitempty"`  // verifier script, default "puppeteer_verifier.js"
+	PlaywrightHeadless        bool   `json:"playwright-headless,omitempty"`    // Enable Playwright-based headless verification
+	PlaywrightScriptPath      string `json:"playwright-script-path,omitempty"` // verifier script, default "playwright_verifier.js"
+	BrowserEngine             string `json:"browser-engine,omitempty"`         // chromium (default), firefox or webkit; the latter two run through Playwright
+	DisableSandbox            bool   `json:"disable-sandbox,omitempty"`
+	ScreenshotQuality         int    `json:"screenshot-quality,omitempty"`
 	OnlyPoC                   string `json:"only-poc,omitempty"`
 	FollowRedirect            bool   `json:"follow-redirects,omitempty"`
 	WAFName                   string `json:"waf-name,omitempty"`
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -16,6 +18,7 @@ type PoC struct {
 	Evidence        string `json:"evidence"`
 	CWE             string `json:"cwe"`
 	Severity        string `json:"severity"`
+	SinkClass       string `json:"sink_class,omitempty"` // see payload.ClassifyPayload
 	MessageID       int64  `json:"message_id,omitempty"`
 	MessageStr      string `json:"message_str,omitempty"`
 	RawHTTPRequest  string `json:"raw_request,omitempty"`
@@ -42,12 +45,115 @@ type PoC struct {
 type Result struct {
 	Logs      []string      `json:"logs"`
 	PoCs      []PoC         `json:"pocs"`
+	AllPoCs   []PoC         `json:"all_pocs,omitempty"` // PoCs before Dedup, nil if never deduplicated
 	Params    []ParamResult `json:"params"`
 	Duration  time.Duration `json:"duration"`
 	StartTime time.Time     `json:"start_time"`
 	EndTime   time.Time     `json:"end_time"`
 }
 
+// SeverityScore maps Severity to a number for ordering: info 1, low 2,
+// medium 3, high 4 and critical 5, case-insensitively. An unknown or empty
+// severity scores 0.
+func (p PoC) SeverityScore() int {
+	switch strings.ToLower(strings.TrimSpace(p.Severity)) {
+	case "critical":
+		return 5
+	case "high":
+		return 4
+	case "medium":
+		return 3
+	case "low":
+		return 2
+	case "info":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// outranks reports whether p is a better representative of a finding than q:
+// a higher severity wins, then execution confirmed in a browser.
+func (p PoC) outranks(q PoC) bool {
+	if sp, sq := p.SeverityScore(), q.SeverityScore(); sp != sq {
+		return sp > sq
+	}
+	return p.browserConfirmed() && !q.browserConfirmed()
+}
+
+// browserConfirmed reports whether a browser saw the PoC's payload execute
+func (p PoC) browserConfirmed() bool {
+	return p.BrowserValidated && p.ExecutionDetected
+}
+
+// Dedup collapses PoCs that share a parameter, injection type and execution
+// context into one, keeping the highest severity and preferably browser
+// validated PoC of each group. Groups keep the position of their first PoC.
+// The list from before the first call is kept in AllPoCs.
+func (r *Result) Dedup() {
+	type key struct{ param, injectType, execContext string }
+	if r.AllPoCs == nil {
+		r.AllPoCs = append([]PoC{}, r.PoCs...)
+	}
+
+	index := make(map[key]int)
+	deduped := make([]PoC, 0, len(r.PoCs))
+	for _, poc := range r.PoCs {
+		k := key{poc.Param, poc.InjectType, poc.ExecutionContext}
+		if i, ok := index[k]; ok {
+			if poc.outranks(deduped[i]) {
+				deduped[i] = poc
+			}
+			continue
+		}
+		index[k] = len(deduped)
+		deduped = append(deduped, poc)
+	}
+	r.PoCs = deduped
+}
+
+// DedupParams collapses ParamResults that share a name, type and reflected
+// point, as a crawl reports the same parameter of an endpoint once per URL. The
+// first occurrence of each group is kept, with its ReflectedCode, in place.
+func (r *Result) DedupParams() {
+	type key struct{ name, paramType, reflectedPoint string }
+	seen := make(map[key]bool)
+	deduped := make([]ParamResult, 0, len(r.Params))
+	for _, p := range r.Params {
+		k := key{p.Name, p.Type, p.ReflectedPoint}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		deduped = append(deduped, p)
+	}
+	r.Params = deduped
+}
+
+// SortBySeverity orders PoCs by descending SeverityScore. Within a severity,
+// PoCs confirmed in a browser come first; otherwise the order is kept.
+func (r *Result) SortBySeverity() {
+	sort.SliceStable(r.PoCs, func(i, j int) bool {
+		return r.PoCs[i].outranks(r.PoCs[j])
+	})
+}
+
+// FilterBySeverity returns a copy of r holding only the PoCs whose severity
+// scores at least as high as minSeverity, e.g. "high" to forward high and critical
+// findings. Logs, Params, AllPoCs and the timing are kept as they are. An empty
+// or unknown minSeverity matches every PoC.
+func (r Result) FilterBySeverity(minSeverity string) Result {
+	threshold := PoC{Severity: minSeverity}.SeverityScore()
+	filtered := r
+	filtered.PoCs = make([]PoC, 0, len(r.PoCs))
+	for _, poc := range r.PoCs {
+		if poc.SeverityScore() >= threshold {
+			filtered.PoCs = append(filtered.PoCs, poc)
+		}
+	}
+	return filtered
+}
+
 type ParamResult struct {
 	Name           string
 	Type           string
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResult_Dedup(t *testing.T) {
+	pocs := []PoC{
+		{Param: "q", InjectType: "inHTML", Payload: "a", Severity: "Medium"},
+		{Param: "id", InjectType: "inATTR", Payload: "b", Severity: "Medium"},
+		{Param: "q", InjectType: "inHTML", Payload: "c", Severity: "High"},
+		{Param: "q", InjectType: "inHTML", Payload: "d", Severity: "High", BrowserValidated: true, ExecutionDetected: true},
+		{Param: "q", InjectType: "inHTML", Payload: "e", Severity: "high"},
+		{Param: "q", InjectType: "inHTML", ExecutionContext: "javascript", Payload: "f", Severity: "Low"},
+		{Param: "id", InjectType: "inATTR", Payload: "g", Severity: "Low", BrowserValidated: true, ExecutionDetected: true},
+	}
+	r := &Result{PoCs: append([]PoC{}, pocs...)}
+	r.Dedup()
+
+	var got []string
+	for _, poc := range r.PoCs {
+		got = append(got, poc.Payload)
+	}
+	if want := []string{"d", "b", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dedup() kept payloads %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(r.AllPoCs, pocs) {
+		t.Errorf("Dedup() did not keep the raw list in AllPoCs")
+	}
+
+	r.Dedup()
+	if len(r.AllPoCs) != len(pocs) {
+		t.Errorf("second Dedup() overwrote AllPoCs with %d PoCs", len(r.AllPoCs))
+	}
+}
+
+func TestResult_DedupParams(t *testing.T) {
+	r := &Result{Params: []ParamResult{
+		{Name: "q", Type: "URL", ReflectedPoint: "/html/body", ReflectedCode: "first"},
+		{Name: "id", Type: "URL", ReflectedPoint: "/html/body"},
+		{Name: "q", Type: "URL", ReflectedPoint: "/html/body", ReflectedCode: "second"},
+		{Name: "q", Type: "FORM", ReflectedPoint: "/html/body"},
+		{Name: "q", Type: "URL", ReflectedPoint: "/html/head/script"},
+	}}
+	r.DedupParams()
+
+	if len(r.Params) != 4 {
+		t.Fatalf("DedupParams() kept %d params, want 4: %+v", len(r.Params), r.Params)
+	}
+	if r.Params[0].ReflectedCode != "first" || r.Params[1].Name != "id" {
+		t.Errorf("DedupParams() = %+v, want the first occurrences in order", r.Params)
+	}
+}
+
+func TestPoC_SeverityScore(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{"Critical", 5},
+		{"high", 4},
+		{" Medium ", 3},
+		{"LOW", 2},
+		{"info", 1},
+		{"", 0},
+		{"urgent", 0},
+	}
+	for _, tt := range tests {
+		if got := (PoC{Severity: tt.severity}).SeverityScore(); got != tt.want {
+			t.Errorf("SeverityScore(%q) = %d, want %d", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestResult_SortBySeverity(t *testing.T) {
+	r := &Result{PoCs: []PoC{
+		{Payload: "low", Severity: "Low"},
+		{Payload: "medium", Severity: "Medium"},
+		{Payload: "high-reflected", Severity: "High"},
+		{Payload: "unknown"},
+		{Payload: "high-validated", Severity: "High", BrowserValidated: true, ExecutionDetected: true},
+		{Payload: "medium-2", Severity: "medium"},
+	}}
+	r.SortBySeverity()
+
+	var got []string
+	for _, poc := range r.PoCs {
+		got = append(got, poc.Payload)
+	}
+	want := []string{"high-validated", "high-reflected", "medium", "medium-2", "low", "unknown"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortBySeverity() order = %q, want %q", got, want)
+	}
+}
+
+func TestPoC_JSONOmitsBrowserFields(t *testing.T) {
+	// empty but non-nil slices must not show up either
+	poc := PoC{Type: "R", Param: "q", JSConsoleLogs: []string{}, JSConsoleErrors: []string{}}
+	out, err := json.Marshal(poc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"browser_validated", "execution_", "screenshot_", "js_console_", "validation_timestamp", "beef_"} {
+		if strings.Contains(string(out), `"`+field) {
+			t.Errorf("unvalidated PoC encodes %s fields: %s", field, out)
+		}
+	}
+
+	poc.BrowserValidated, poc.ScreenshotBase64 = true, "aGk="
+	out, _ = json.Marshal(poc)
+	if !strings.Contains(string(out), `"browser_validated":true`) || !strings.Contains(string(out), `"screenshot_base64":"aGk="`) {
+		t.Errorf("validated PoC lost its browser fields: %s", out)
+	}
+}
+
+func TestResult_FilterBySeverity(t *testing.T) {
+	r := Result{
+		Logs:     []string{"scan started"},
+		Params:   []ParamResult{{Name: "q"}},
+		Duration: time.Second,
+		PoCs: []PoC{
+			{Payload: "low", Severity: "Low"},
+			{Payload: "critical", Severity: "Critical"},
+			{Payload: "high", Severity: "high"},
+			{Payload: "unknown"},
+		},
+	}
+	tests := []struct {
+		min  string
+		want []string
+	}{
+		{min: "High", want: []string{"critical", "high"}},
+		{min: "critical", want: []string{"critical"}},
+		{min: "low", want: []string{"low", "critical", "high"}},
+		{min: "", want: []string{"low", "critical", "high", "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.min, func(t *testing.T) {
+			filtered := r.FilterBySeverity(tt.min)
+			got := []string{}
+			for _, poc := range filtered.PoCs {
+				got = append(got, poc.Payload)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterBySeverity(%q) kept %q, want %q", tt.min, got, tt.want)
+			}
+			if !reflect.DeepEqual(filtered.Logs, r.Logs) || !reflect.DeepEqual(filtered.Params, r.Params) || filtered.Duration != r.Duration {
+				t.Errorf("FilterBySeverity(%q) did not keep logs, params and timing", tt.min)
+			}
+		})
+	}
+	if len(r.PoCs) != 4 {
+		t.Errorf("FilterBySeverity() modified the original Result")
+	}
+}
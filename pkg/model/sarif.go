@@ -0,0 +1,228 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolURI = "https://github.com/hahwul/dalfox"
+
+	// sarifDefaultRule is the ruleId of PoCs without a CWE
+	sarifDefaultRule = "XSS"
+)
+
+// sarifLog is the subset of the SARIF 2.1.0 object model written by ToSARIF
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool      sarifTool       `json:"tool"`
+	Results   []sarifResult   `json:"results"`
+	Artifacts []sarifArtifact `json:"artifacts,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID      string            `json:"ruleId"`
+	Level       string            `json:"level"`
+	Message     sarifMessage      `json:"message"`
+	Locations   []sarifLocation   `json:"locations"`
+	Attachments []sarifAttachment `json:"attachments,omitempty"`
+	Properties  map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI   string `json:"uri"`
+	Index *int   `json:"index,omitempty"`
+}
+
+type sarifAttachment struct {
+	Description      sarifMessage          `json:"description"`
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+	Location sarifArtifactLocation `json:"location"`
+	MimeType string                `json:"mimeType,omitempty"`
+	Contents *sarifMessage         `json:"contents,omitempty"`
+}
+
+// ToSARIF renders the PoCs of r as a SARIF 2.1.0 log, e.g. for GitHub code
+// scanning. Each PoC becomes a result whose ruleId is its CWE and whose level
+// follows its severity. The raw request and response are embedded as run
+// artifacts and attached to the result, as is the screenshot of browser
+// validated PoCs.
+func (r *Result) ToSARIF() ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Dalfox",
+			InformationURI: sarifToolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	addArtifact := func(a sarifArtifact, description string) sarifAttachment {
+		index := len(run.Artifacts)
+		run.Artifacts = append(run.Artifacts, a)
+		loc := a.Location
+		loc.Index = &index
+		return sarifAttachment{Description: sarifMessage{Text: description}, ArtifactLocation: loc}
+	}
+
+	for i, poc := range r.PoCs {
+		ruleID := sarifRuleID(poc.CWE)
+		if !rules[ruleID] {
+			rules[ruleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(ruleID))
+		}
+
+		res := sarifResult{
+			RuleID:  ruleID,
+			Level:   sarifLevel(poc.Severity),
+			Message: sarifMessage{Text: sarifResultMessage(poc)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: poc.Data}},
+			}},
+			Properties: map[string]any{
+				"type":       poc.Type,
+				"injectType": poc.InjectType,
+				"method":     poc.Method,
+				"param":      poc.Param,
+				"payload":    poc.Payload,
+				"severity":   poc.Severity,
+			},
+		}
+		if poc.Evidence != "" {
+			res.Properties["evidence"] = poc.Evidence
+		}
+
+		if poc.RawHTTPRequest != "" {
+			res.Attachments = append(res.Attachments, addArtifact(sarifArtifact{
+				Location: sarifArtifactLocation{URI: fmt.Sprintf("poc-%d/request.http", i+1)},
+				MimeType: "message/http",
+				Contents: &sarifMessage{Text: poc.RawHTTPRequest},
+			}, "HTTP request"))
+		}
+		if poc.RawHTTPResponse != "" {
+			res.Attachments = append(res.Attachments, addArtifact(sarifArtifact{
+				Location: sarifArtifactLocation{URI: fmt.Sprintf("poc-%d/response.http", i+1)},
+				MimeType: "message/http",
+				Contents: &sarifMessage{Text: poc.RawHTTPResponse},
+			}, "HTTP response"))
+		}
+		if poc.BrowserValidated && poc.ScreenshotPath != "" {
+			res.Attachments = append(res.Attachments, addArtifact(sarifArtifact{
+				Location: sarifArtifactLocation{URI: sarifFileURI(poc.ScreenshotPath)},
+			}, "Screenshot of the execution"))
+		}
+
+		run.Results = append(run.Results, res)
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
+
+// sarifRuleID returns the ruleId for a PoC's CWE
+func sarifRuleID(cwe string) string {
+	if cwe = strings.TrimSpace(cwe); cwe == "" {
+		return sarifDefaultRule
+	}
+	return strings.ToUpper(cwe)
+}
+
+// sarifRuleFor describes the rule with the given ruleId
+func sarifRuleFor(id string) sarifRule {
+	rule := sarifRule{ID: id, Name: "CrossSiteScripting", ShortDescription: sarifMessage{Text: "Cross-site scripting"}}
+	if num, ok := strings.CutPrefix(id, "CWE-"); ok {
+		rule.HelpURI = "https://cwe.mitre.org/data/definitions/" + num + ".html"
+		switch num {
+		case "79":
+			rule.ShortDescription.Text = "Improper neutralization of input during web page generation (cross-site scripting)"
+		case "83":
+			rule.Name = "ScriptInAttributes"
+			rule.ShortDescription.Text = "Improper neutralization of script in attributes in a web page"
+		}
+	}
+	return rule
+}
+
+// sarifLevel maps a PoC severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "low", "info":
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// sarifResultMessage is the human readable summary of a PoC
+func sarifResultMessage(poc PoC) string {
+	kind := "Reflected payload"
+	switch poc.Type {
+	case "V":
+		kind = "Verified XSS"
+	case "G":
+		kind = "Grepped pattern"
+	}
+	msg := kind + " via " + poc.InjectType
+	if poc.Param != "" {
+		msg += " in parameter " + poc.Param
+	}
+	if poc.MessageStr != "" {
+		msg += ": " + poc.MessageStr
+	}
+	return msg
+}
+
+// sarifFileURI turns a local path into a file URI
+func sarifFileURI(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	if strings.HasPrefix(path, "/") {
+		return "file://" + path
+	}
+	return path
+}
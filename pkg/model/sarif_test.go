@@ -0,0 +1,88 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResult_ToSARIF(t *testing.T) {
+	r := &Result{PoCs: []PoC{
+		{
+			Type:             "V",
+			InjectType:       "inHTML",
+			Method:           "GET",
+			Data:             "https://example.com/?q=%3Csvg%3E",
+			Param:            "q",
+			Payload:          "<svg onload=alert(1)>",
+			CWE:              "CWE-79",
+			Severity:         "High",
+			RawHTTPRequest:   "GET /?q=%3Csvg%3E HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			RawHTTPResponse:  "HTTP/1.1 200 OK\r\n\r\n<svg onload=alert(1)>",
+			BrowserValidated: true,
+			ScreenshotPath:   "/tmp/snapshots/poc.jpg",
+		},
+		{Type: "R", InjectType: "inATTR", Data: "https://example.com/b", CWE: "cwe-83", Severity: "Medium"},
+		{Type: "G", InjectType: "grep", Data: "https://example.com/c", Severity: "Low"},
+		{Type: "V", InjectType: "inJS", Data: "https://example.com/d", CWE: "CWE-79", Severity: "High", ScreenshotPath: "/tmp/x.jpg"},
+	}}
+
+	out, err := r.ToSARIF()
+	if err != nil {
+		t.Fatalf("ToSARIF() error = %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out, &log); err != nil {
+		t.Fatalf("ToSARIF() produced invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version = %q, runs = %d", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	if want := []string{"CWE-79", "CWE-83", "XSS"}; len(ruleIDs) != len(want) || ruleIDs[0] != want[0] || ruleIDs[1] != want[1] || ruleIDs[2] != want[2] {
+		t.Errorf("rules = %v, want %v", ruleIDs, want)
+	}
+
+	tests := []struct {
+		ruleID      string
+		level       string
+		attachments int
+	}{
+		{"CWE-79", "error", 3},
+		{"CWE-83", "warning", 0},
+		{"XSS", "note", 0},
+		// the screenshot only counts for browser validated PoCs
+		{"CWE-79", "error", 0},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("results = %d, want %d", len(run.Results), len(tests))
+	}
+	for i, tt := range tests {
+		res := run.Results[i]
+		if res.RuleID != tt.ruleID || res.Level != tt.level || len(res.Attachments) != tt.attachments {
+			t.Errorf("result %d = {%s %s %d attachments}, want {%s %s %d attachments}",
+				i, res.RuleID, res.Level, len(res.Attachments), tt.ruleID, tt.level, tt.attachments)
+		}
+	}
+
+	first := run.Results[0]
+	if got := first.Locations[0].PhysicalLocation.ArtifactLocation.URI; got != r.PoCs[0].Data {
+		t.Errorf("location = %q, want %q", got, r.PoCs[0].Data)
+	}
+	for _, a := range first.Attachments {
+		idx := a.ArtifactLocation.Index
+		if idx == nil || *idx >= len(run.Artifacts) {
+			t.Fatalf("attachment %q does not reference a run artifact", a.Description.Text)
+		}
+	}
+	if got := run.Artifacts[*first.Attachments[0].ArtifactLocation.Index].Contents; got == nil || got.Text != r.PoCs[0].RawHTTPRequest {
+		t.Errorf("request artifact contents = %+v, want the raw request", got)
+	}
+	if got := run.Artifacts[*first.Attachments[2].ArtifactLocation.Index].Location.URI; got != "file:///tmp/snapshots/poc.jpg" {
+		t.Errorf("screenshot artifact = %q", got)
+	}
+}
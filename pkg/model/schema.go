@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaDefs are the types described under $defs of ResultSchema, by name
+var schemaDefs = map[reflect.Type]string{
+	reflect.TypeOf(PoC{}):         "PoC",
+	reflect.TypeOf(ParamResult{}): "ParamResult",
+}
+
+// ResultSchema returns a JSON Schema (draft 2020-12) of the JSON encoding of
+// Result, with PoC and ParamResult under $defs. It is derived from the struct
+// definitions, so it cannot drift from what the scanner writes: fields without
+// omitempty are required, time.Time is a date-time string and time.Duration an
+// integer of nanoseconds.
+func ResultSchema() []byte {
+	defs := make(map[string]any, len(schemaDefs))
+	for t, name := range schemaDefs {
+		defs[name] = structSchema(t)
+	}
+	schema := structSchema(reflect.TypeOf(Result{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = "https://github.com/hahwul/dalfox/schemas/result.json"
+	schema["title"] = "dalfox Result"
+	schema["$defs"] = defs
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		// the schema only holds maps, slices and strings
+		panic(err)
+	}
+	return out
+}
+
+// structSchema describes the exported fields of struct type t as encoding/json
+// would encode them
+func structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = typeSchema(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// typeSchema describes a field of type t, referencing the types of schemaDefs
+func typeSchema(t reflect.Type) map[string]any {
+	if name, ok := schemaDefs[t]; ok {
+		return map[string]any{"$ref": "#/$defs/" + name}
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": "integer", "description": "nanoseconds"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		// nil slices are encoded as null
+		return map[string]any{"type": []string{"array", "null"}, "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fill sets every field of v, recursively, to a non-zero value
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	}
+}
+
+// checkSchema reports where value, decoded from JSON, does not match schema
+func checkSchema(t *testing.T, path string, schema map[string]any, defs map[string]any, value any) {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = defs[ref[len("#/$defs/"):]].(map[string]any)
+	}
+	var types []any
+	switch typ := schema["type"].(type) {
+	case string:
+		types = []any{typ}
+	case []any:
+		types = typ
+	}
+	kind := map[reflect.Kind]string{reflect.String: "string", reflect.Bool: "boolean", reflect.Float64: "integer", reflect.Slice: "array", reflect.Map: "object"}[reflect.TypeOf(value).Kind()]
+	found := false
+	for _, typ := range types {
+		found = found || typ == kind
+	}
+	if !found {
+		t.Errorf("%s: %s value, schema allows %v", path, kind, types)
+		return
+	}
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			checkSchema(t, path+"[]", schema["items"].(map[string]any), defs, item)
+		}
+	case map[string]any:
+		props := schema["properties"].(map[string]any)
+		for key, item := range v {
+			prop, ok := props[key].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s is not in the schema", path, key)
+				continue
+			}
+			checkSchema(t, path+"."+key, prop, defs, item)
+		}
+		for key := range props {
+			if _, ok := v[key]; !ok {
+				t.Errorf("%s.%s is in the schema but was not encoded", path, key)
+			}
+		}
+		for _, key := range schema["required"].([]any) {
+			if _, ok := v[key.(string)]; !ok {
+				t.Errorf("%s.%s is required but missing", path, key)
+			}
+		}
+	}
+}
+
+func TestResultSchema(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal(ResultSchema(), &schema); err != nil {
+		t.Fatalf("ResultSchema() is not JSON: %v", err)
+	}
+	defs := schema["$defs"].(map[string]any)
+	if _, ok := defs["PoC"]; !ok {
+		t.Fatalf("ResultSchema() has no PoC definition")
+	}
+
+	// every field of a fully populated Result must be described and vice versa
+	var r Result
+	fill(reflect.ValueOf(&r).Elem())
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value map[string]any
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		t.Fatal(err)
+	}
+	checkSchema(t, "result", schema, defs, value)
+}
@@ -10,10 +10,12 @@ import (
 )
 
 // performDiscovery handles the discovery phase including static, parameter, and BAV analysis.
-func performDiscovery(target string, options model.Options, rl *rateLimiter) (map[string]string, map[int]string, map[string]model.ParamResult) {
+// It also returns the name of the WAF detected by the parameter analysis, or "".
+func performDiscovery(target string, options model.Options, rl *rateLimiter) (map[string]string, map[int]string, map[string]model.ParamResult, string) {
 	policy := make(map[string]string)
 	pathReflection := make(map[int]string)
 	params := make(map[string]model.ParamResult)
+	var wafName string
 
 	var wait sync.WaitGroup
 	task := 3
@@ -36,7 +38,7 @@ func performDiscovery(target string, options model.Options, rl *rateLimiter) (ma
 	}()
 	go func() {
 		defer wait.Done()
-		params = ParameterAnalysis(target, options, rl)
+		params, wafName = ParameterAnalysis(target, options, rl)
 		pa = options.AuroraObject.Green(pa).String()
 		printing.DalLog("SYSTEM", "["+sa+pa+bav+"] Waiting for analysis to complete", options)
 	}()
@@ -59,5 +61,5 @@ func performDiscovery(target string, options model.Options, rl *rateLimiter) (ma
 		s.Stop()
 	}
 
-	return policy, pathReflection, params
+	return policy, pathReflection, params, wafName
 }
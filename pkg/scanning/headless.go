@@ -1,102 +1,426 @@
 package scanning
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hahwul/dalfox/v2/internal/browser"
 	"github.com/hahwul/dalfox/v2/pkg/model"
 )
 
-var browserMgr *browser.Manager
-
-func init() {
-	// Initialize browser manager (kept for compatibility but will be replaced by Puppeteer)
-	browserMgr = browser.NewManager(browser.BrowserConfig{
-		HeadlessMode:         true,
-		DisableSandbox:       false,
-		Timeout:              30,
-		WaitForAlertOnlyTime: 5,
-		ChromiumBinaryPath:   "",
-		TakeScreenshots:      true,
+// verifierStderrLines is how much of a verifier's stderr a failure log keeps
+const verifierStderrLines = 10
+
+// verifierTimeoutMargin is added to the headless timeout for a run of a node
+// verifier, covering node and browser startup before its own page timeout.
+// A variable so tests can shorten it.
+var verifierTimeoutMargin = 15 * time.Second
+
+// browserMgr is created on the first headless validation, so scans that never
+// reach the browser do not pay for it. browserMgrErr is the error initializing
+// it. Guarded by browserMgrMu, like logger.
+var (
+	browserMgr    *browser.Manager
+	browserMgrErr error
+	logger        Logger
+	browserMgrMu  sync.Mutex
+)
+
+// browserConfig builds the configuration of the shared manager, replaced in tests
+var browserConfig = browser.ConfigFromOptions
+
+// Logger receives the diagnostics of headless validation, like failing node
+// verifiers and saved screenshots. A *slog.Logger can be used as is.
+type Logger = browser.Logger
+
+// SetLogger routes the diagnostics of headless validation, including those of
+// the browser manager created afterwards, to l. A nil l restores the default,
+// slog.Default(). A manager passed to SetBrowserManager keeps its own logger.
+func SetLogger(l Logger) {
+	browserMgrMu.Lock()
+	logger = l
+	browserMgrMu.Unlock()
+}
+
+// headlessLogger returns the Logger set with SetLogger or the default
+func headlessLogger() Logger {
+	browserMgrMu.Lock()
+	defer browserMgrMu.Unlock()
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// browserManager returns the shared manager, creating and initializing it from
+// options on first use. Later calls reuse it regardless of their options, and
+// also get the error of a failed initialization, which is logged once.
+func browserManager(options model.Options) (*browser.Manager, error) {
+	browserMgrMu.Lock()
+	if browserMgr != nil {
+		defer browserMgrMu.Unlock()
+		return browserMgr, browserMgrErr
+	}
+	browserMgr = browser.NewManager(browserConfig(options))
+	browserMgr.SetLogger(logger)
+	if err := browserMgr.Initialize(); err != nil {
+		browserMgrErr = fmt.Errorf("initialize browser: %w", err)
+	}
+	m, err := browserMgr, browserMgrErr
+	browserMgrMu.Unlock()
+
+	if err != nil {
+		headlessLogger().Error("Headless validation is unavailable", "error", err)
+	}
+	return m, err
+}
+
+// SetBrowserManager replaces the manager used for headless validation, e.g. to
+// share one configured by the caller or to inject one in tests. The previous
+// manager is not shut down.
+func SetBrowserManager(m *browser.Manager) {
+	browserMgrMu.Lock()
+	browserMgr, browserMgrErr = m, nil
+	browserMgrMu.Unlock()
+}
+
+// Validator is a headless browser backend confirming that the payload in url
+// executes. payload and contextStr only label the evidence.
+type Validator interface {
+	Validate(url, payload, contextStr string) *browser.ValidationResult
+}
+
+// urlsValidator is implemented by validators that check many urls more
+// efficiently than one Validate call per url, see validateURLs
+type urlsValidator interface {
+	ValidateURLsFunc(urls []string, payload, contextStr string, concurrency int, fn func(i int, result *browser.ValidationResult))
+}
+
+// headlessValidator returns the backend selected by options: Playwright for a
+// --browser-engine other than chromium, Puppeteer if --puppeteer-headless flag
+// is set, Playwright if --playwright-headless is, otherwise the shared chromedp
+// manager. An unknown --browser-engine fails every validation.
+func headlessValidator(options model.Options) Validator {
+	engine, err := browser.ParseEngine(options.BrowserEngine)
+	if err != nil {
+		return errorValidator{err: err}
+	}
+	switch {
+	case engine != browser.EngineChromium:
+		return playwrightValidator{options: options}
+	case options.PuppeteerHeadless:
+		return puppeteerValidator{options: options}
+	case options.PlaywrightHeadless:
+		return playwrightValidator{options: options}
+	}
+	m, err := browserManager(options)
+	return chromedpValidator{manager: m, err: err}
+}
+
+// errorValidator fails every validation with err
+type errorValidator struct {
+	err error
+}
+
+func (v errorValidator) Validate(url, payload, contextStr string) *browser.ValidationResult {
+	return &browser.ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: v.err}
+}
+
+// CheckXSSWithHeadless is XSS Testing with headless browser, using the backend
+// selected by headlessValidator. It returns the validation result, whose proofs
+// can be applied to a PoC with ExecutionProof.ApplyToPoC, and whether a dialog
+// fired.
+func CheckXSSWithHeadless(url string, options model.Options) (*browser.ValidationResult, bool) {
+	result := headlessValidator(options).Validate(url, "[headless-check]", string(browser.ContextHeadless))
+	return result, reportHeadlessResult(result)
+}
+
+// CheckURLsWithHeadless runs CheckXSSWithHeadless for every url using
+// headlessConcurrency workers. fn receives the index of each url, its result and
+// whether a dialog fired as soon as that url is done, one call at a time.
+func CheckURLsWithHeadless(urls []string, options model.Options, fn func(i int, result *browser.ValidationResult, found bool)) {
+	validateURLs(headlessValidator(options), urls, headlessConcurrency(options), func(i int, result *browser.ValidationResult) {
+		fn(i, result, reportHeadlessResult(result))
 	})
-	browserMgr.Initialize()
 }
 
-// CheckXSSWithHeadless is XSS Testing with headless browser
-// Uses Puppeteer if --puppeteer-headless flag is set, otherwise uses chromedp
-func CheckXSSWithHeadless(url string, options model.Options) bool {
-	if options.PuppeteerHeadless {
-		return checkXSSWithPuppeteer(url, options)
+// validateURLs validates every url with v using concurrency workers and passes
+// each result to fn with the index of its url as soon as it is available. fn is
+// called by one worker at a time; validateURLs returns after the last call.
+func validateURLs(v Validator, urls []string, concurrency int, fn func(i int, result *browser.ValidationResult)) {
+	if batch, ok := v.(urlsValidator); ok {
+		batch.ValidateURLsFunc(urls, "[headless-check]", string(browser.ContextHeadless), concurrency, fn)
+		return
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := v.Validate(urls[i], "[headless-check]", string(browser.ContextHeadless))
+				mu.Lock()
+				fn(i, result)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// headlessConcurrency returns the number of parallel headless validations:
+// options.HeadlessConcurrency when set, otherwise half the workers capped at 10.
+func headlessConcurrency(options model.Options) int {
+	if options.HeadlessConcurrency > 0 {
+		return options.HeadlessConcurrency
+	}
+	n := options.Concurrence / 2
+	if n < 1 {
+		n = 1
+	}
+	if n > 10 {
+		n = 10
+	}
+	return n
+}
+
+// verifierResult is the JSON printed by puppeteer_verifier.js and
+// playwright_verifier.js
+type verifierResult struct {
+	IsVulnerable      bool `json:"isVulnerable"`
+	ExecutionDetected bool `json:"executionDetected"`
+	ExecutionProofs   []struct {
+		PayloadSHA256    string    `json:"payloadSHA256"`
+		ExecutionType    string    `json:"executionType"`
+		ExecutedAt       time.Time `json:"executedAt"`
+		Evidence         string    `json:"evidence"`
+		PageURL          string    `json:"pageURL"`
+		PageTitle        string    `json:"pageTitle"`
+		ScreenshotPath   string    `json:"screenshotPath"`
+		ScreenshotData   string    `json:"screenshotData"` // base64
+		ExecutionContext string    `json:"executionContext"`
+	} `json:"executionProofs"`
+	ValidationDuration int64  `json:"validationDuration"` // milliseconds
+	Error              string `json:"error,omitempty"`
+}
+
+// validationResult converts the verifier output to the result the chromedp
+// path returns
+func (r verifierResult) validationResult() *browser.ValidationResult {
+	result := &browser.ValidationResult{
+		IsVulnerable:       r.IsVulnerable,
+		ExecutionDetected:  r.ExecutionDetected,
+		ValidationDuration: time.Duration(r.ValidationDuration) * time.Millisecond,
+	}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
 	}
-	return checkXSSWithChromedp(url, options)
+	for _, p := range r.ExecutionProofs {
+		screenshot, _ := base64.StdEncoding.DecodeString(p.ScreenshotData)
+		result.ExecutionProofs = append(result.ExecutionProofs, browser.ExecutionProof{
+			PayloadSHA256:    p.PayloadSHA256,
+			ExecutionType:    p.ExecutionType,
+			ExecutedAt:       p.ExecutedAt,
+			Evidence:         p.Evidence,
+			DialogCount:      1,
+			PageURL:          p.PageURL,
+			PageTitle:        p.PageTitle,
+			ExecutionContext: p.ExecutionContext,
+			ScreenshotPath:   p.ScreenshotPath,
+			ScreenshotData:   screenshot,
+		})
+		if result.PageTitle == "" {
+			result.PageTitle = p.PageTitle
+		}
+	}
+	return result
+}
+
+// puppeteerValidator runs puppeteer_verifier.js with node for every validation
+type puppeteerValidator struct {
+	options model.Options
 }
 
-// checkXSSWithPuppeteer uses Puppeteer for headless verification
+// Validate uses Puppeteer for headless verification
 // Takes JPG screenshots ONLY after alert/confirm/prompt execution
-func checkXSSWithPuppeteer(url string, options model.Options) bool {
+func (v puppeteerValidator) Validate(url, payload, contextStr string) *browser.ValidationResult {
+	node, script, err := puppeteerCommand(v.options)
+	if err != nil {
+		headlessLogger().Error("Puppeteer verification failed", "error", err)
+		return &browser.ValidationResult{Error: err}
+	}
+	result := runNodeVerifier("Puppeteer", node, script, url, payload, v.options.HeadlessTimeout)
+	if result.ExecutionDetected {
+		result.Engine = browser.EngineChromium
+	}
+	return result
+}
+
+// playwrightValidator runs playwright_verifier.js with node for every validation
+type playwrightValidator struct {
+	options model.Options
+}
+
+// Validate uses Playwright for headless verification in the engine selected by
+// --browser-engine, taking JPG screenshots after alert/confirm/prompt execution
+// like the Puppeteer verifier
+func (v playwrightValidator) Validate(url, payload, contextStr string) *browser.ValidationResult {
+	node, script, err := nodeVerifierCommand(v.options.PuppeteerNodePath, v.options.PlaywrightScriptPath, "playwright_verifier.js")
+	if err != nil {
+		headlessLogger().Error("Playwright verification failed", "error", err)
+		return &browser.ValidationResult{Error: err}
+	}
+	engine, err := browser.ParseEngine(v.options.BrowserEngine)
+	if err != nil {
+		return &browser.ValidationResult{Error: err}
+	}
+	result := runNodeVerifier("Playwright", node, script, url, payload, v.options.HeadlessTimeout, engine)
+	if result.ExecutionDetected {
+		result.Engine = engine
+	}
+	return result
+}
+
+// runNodeVerifier runs a verifier script with node and converts its JSON output.
+// name labels the logs. The script gets the headless timeout in seconds, then
+// extra arguments, and is killed when it overruns the timeout by
+// verifierTimeoutMargin.
+func runNodeVerifier(name, node, script, url, payload string, timeout int, extra ...string) *browser.ValidationResult {
 	// Generate a unique session ID for this validation
 	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
 
-	// Call Puppeteer verification script
-	cmd := exec.Command("node", "puppeteer_verifier.js",
+	if timeout <= 0 {
+		timeout = 30
+	}
+	limit := time.Duration(timeout)*time.Second + verifierTimeoutMargin
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+
+	// Call verification script; it is killed once ctx expires
+	args := []string{script,
 		url,
-		"[headless-check]",
+		payload,
 		sessionID,
-		strconv.Itoa(options.HeadlessTimeout),
-		strconv.Itoa(options.HeadlessTimeout/6), // waitTime as fraction of timeout
-	)
+		strconv.Itoa(timeout),
+		strconv.Itoa(timeout / 6), // waitTime as fraction of timeout
+	}
+	cmd := exec.CommandContext(ctx, node, append(args, extra...)...)
+	// Chromium children inherit the output pipes; stop waiting for them after a kill
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
 	output, err := cmd.Output()
 	if err != nil {
-		log.Printf("Puppeteer verification failed: %v", err)
-		return false
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("killed after %s: %w", limit, err)
+		}
+		headlessLogger().Error(name+" verification failed", "error", err, "stderr", stderrTail(stderr.Bytes(), verifierStderrLines))
+		return &browser.ValidationResult{Error: err}
 	}
 
 	// Parse JSON result
-	var result struct {
-		IsVulnerable      bool `json:"isVulnerable"`
-		ExecutionDetected bool `json:"executionDetected"`
-		ExecutionProofs   []struct {
-			ScreenshotPath string `json:"screenshotPath"`
-		} `json:"executionProofs"`
-		Error string `json:"error,omitempty"`
+	var result verifierResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		headlessLogger().Error("Failed to parse "+name+" result", "error", err, "stderr", stderrTail(stderr.Bytes(), verifierStderrLines))
+		return &browser.ValidationResult{Error: err}
 	}
+	return result.validationResult()
+}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		log.Printf("Failed to parse Puppeteer result: %v", err)
-		return false
+// puppeteerCommand returns the node binary and verifier script configured in
+// options, defaulting to node from PATH and puppeteer_verifier.js in the working
+// directory.
+func puppeteerCommand(options model.Options) (string, string, error) {
+	return nodeVerifierCommand(options.PuppeteerNodePath, options.PuppeteerScriptPath, "puppeteer_verifier.js")
+}
+
+// nodeVerifierCommand returns node, or "node" from PATH when empty, and script,
+// or defaultScript when empty. It fails when the script does not exist, which
+// exec would otherwise only report as a node exit status.
+func nodeVerifierCommand(node, script, defaultScript string) (string, string, error) {
+	if node == "" {
+		node = "node"
+	}
+	if script == "" {
+		script = defaultScript
 	}
+	if _, err := os.Stat(script); err != nil {
+		return "", "", fmt.Errorf("verifier script not found: %w", err)
+	}
+	return node, script, nil
+}
 
-	if result.ExecutionDetected {
-		// Log screenshot if available
-		if len(result.ExecutionProofs) > 0 && result.ExecutionProofs[0].ScreenshotPath != "" {
-			log.Printf("CORE REQUIREMENT: Screenshot saved to %s", result.ExecutionProofs[0].ScreenshotPath)
-		}
-		return true
+// stderrTail returns the last n lines of a process's stderr for the "stderr"
+// attribute of a failure log, or "" when there was no output. Node prints missing
+// modules and Chromium download failures there, usually at the end.
+func stderrTail(stderr []byte, n int) string {
+	lines := strings.Split(strings.TrimSpace(string(stderr)), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return ""
+	}
+	if len(lines) > n {
+		lines = append([]string{"..."}, lines[len(lines)-n:]...)
 	}
+	return strings.Join(lines, "\n")
+}
 
-	return false
+// chromedpValidator validates in tabs of a browser.Manager
+type chromedpValidator struct {
+	manager *browser.Manager
+	err     error // from initializing manager, fails every validation
 }
 
-// checkXSSWithChromedp uses chromedp (original implementation) for headless verification
-func checkXSSWithChromedp(url string, options model.Options) bool {
+// Validate uses chromedp (original implementation) for headless verification
+func (v chromedpValidator) Validate(url, payload, contextStr string) *browser.ValidationResult {
+	if v.err != nil {
+		return &browser.ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: v.err}
+	}
 	// Generate a unique session ID for this validation
 	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
 
 	// Use the new browser manager with screenshot capabilities
-	validationResult := browserMgr.ValidatePayload(sessionID, url, "[headless-check]", "headless")
+	return v.manager.ValidatePayload(sessionID, url, payload, contextStr)
+}
+
+// ValidateURLsFunc validates the urls in parallel tabs of the manager
+func (v chromedpValidator) ValidateURLsFunc(urls []string, payload, contextStr string, concurrency int, fn func(i int, result *browser.ValidationResult)) {
+	if v.err != nil {
+		for i := range urls {
+			fn(i, &browser.ValidationResult{IsVulnerable: false, ExecutionDetected: false, Error: v.err})
+		}
+		return
+	}
+	v.manager.ValidateURLsFunc(urls, payload, contextStr, concurrency, fn)
+}
 
+// reportHeadlessResult logs the screenshot of a confirmed execution and reports
+// whether execution was detected.
+func reportHeadlessResult(validationResult *browser.ValidationResult) bool {
 	if validationResult != nil && validationResult.ExecutionDetected {
-		// CORE REQUIREMENT: Take screenshots ONLY when execution is confirmed
+		// Screenshots are only taken when execution is confirmed
 		if validationResult.ExecutionProofs != nil && len(validationResult.ExecutionProofs) > 0 {
 			proof := validationResult.ExecutionProofs[0]
 			if proof.ScreenshotPath != "" {
-				log.Printf("CORE REQUIREMENT: Screenshot saved to %s", proof.ScreenshotPath)
+				headlessLogger().Info("Screenshot saved", "path", proof.ScreenshotPath)
 			}
 		}
 		return true
@@ -105,8 +429,11 @@ func checkXSSWithChromedp(url string, options model.Options) bool {
 	return false
 }
 
-// GetBrowserManager returns the browser manager instance
-// This allows other parts of the code to access browser validation functionality
+// GetBrowserManager returns the browser manager instance, creating it on first use.
+// This allows other parts of the code to access browser validation functionality.
+// If it failed to initialize, the error is logged and its validations fail with
+// browser.ErrNotInitialized.
 func GetBrowserManager() *browser.Manager {
-	return browserMgr
+	m, _ := browserManager(model.Options{})
+	return m
 }
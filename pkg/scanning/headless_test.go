@@ -1,8 +1,21 @@
 package scanning
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hahwul/dalfox/v2/internal/browser"
 	"github.com/hahwul/dalfox/v2/pkg/model"
 )
 
@@ -52,51 +65,319 @@ func Test_CheckXSSWithHeadless(t *testing.T) {
 			// Skip actual headless browser tests in CI environment
 			t.Skip("Skipping headless browser tests")
 
-			if got := CheckXSSWithHeadless(tt.args.url, tt.args.options); got != tt.want {
+			if _, got := CheckXSSWithHeadless(tt.args.url, tt.args.options); got != tt.want {
 				t.Errorf("CheckXSSWithHeadless() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_setheaders(t *testing.T) {
-	type args struct {
-		host    string
-		headers map[string]interface{}
+func Test_puppeteerCommand(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "verifier.js")
+	if err := os.WriteFile(script, []byte("// verifier"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	node, got, err := puppeteerCommand(model.Options{PuppeteerNodePath: "/opt/node/bin/node", PuppeteerScriptPath: script})
+	if err != nil {
+		t.Fatalf("puppeteerCommand() error = %v", err)
+	}
+	if node != "/opt/node/bin/node" || got != script {
+		t.Errorf("puppeteerCommand() = %q, %q, want the configured paths", node, got)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.js")
+	if _, _, err := puppeteerCommand(model.Options{PuppeteerScriptPath: missing}); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("puppeteerCommand() with a missing script: error = %v, want one naming %s", err, missing)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, _, err := puppeteerCommand(model.Options{}); err == nil || !strings.Contains(err.Error(), "puppeteer_verifier.js") {
+		t.Errorf("puppeteerCommand() default script: error = %v, want one naming puppeteer_verifier.js", err)
+	}
+}
+
+func Test_runNodeVerifier_timeout(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	script := filepath.Join(t.TempDir(), "verifier.sh")
+	if err := os.WriteFile(script, []byte("exec sleep 30\n"), 0644); err != nil {
+		t.Fatal(err)
 	}
+	defer func(orig time.Duration) { verifierTimeoutMargin = orig }(verifierTimeoutMargin)
+	verifierTimeoutMargin = 0
+	SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer SetLogger(nil)
+
+	start := time.Now()
+	res := runNodeVerifier("Puppeteer", sh, script, "http://example.com", "[headless-check]", 1)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runNodeVerifier() returned after %v, want the verifier killed after 1s", elapsed)
+	}
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "killed after 1s") {
+		t.Errorf("runNodeVerifier() error = %v, want it killed after 1s", res.Error)
+	}
+}
+
+func Test_stderrTail(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
+		name   string
+		stderr string
+		want   string
 	}{
-		{
-			name: "Basic Headers Test",
-			args: args{
-				host: "https://example.com",
-				headers: map[string]interface{}{
-					"User-Agent": "Dalfox Test",
-				},
-			},
-		},
-		{
-			name: "Multiple Headers Test",
-			args: args{
-				host: "https://example.com",
-				headers: map[string]interface{}{
-					"User-Agent":      "Dalfox Test",
-					"Accept-Language": "en-US,en;q=0.9",
-					"Cookie":          "test=value",
-				},
-			},
-		},
+		{name: "empty", stderr: "", want: ""},
+		{name: "whitespace only", stderr: "\n  \n", want: ""},
+		{name: "short", stderr: "Error: Cannot find module 'puppeteer'\n", want: "Error: Cannot find module 'puppeteer'"},
+		{name: "truncated", stderr: "1\n2\n3\n4\n", want: "...\n3\n4"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			t.Skip("Skipping headless browser tests")
-
-			tasks := setheaders(tt.args.host, tt.args.headers)
-			if tasks == nil {
-				t.Errorf("setheaders() returned nil tasks")
+			if got := stderrTail([]byte(tt.stderr), 2); got != tt.want {
+				t.Errorf("stderrTail() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_puppeteerValidator_stderr(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	script := filepath.Join(t.TempDir(), "verifier.sh")
+	if err := os.WriteFile(script, []byte("echo \"Error: Cannot find module 'puppeteer'\" >&2\nexit 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	v := puppeteerValidator{options: model.Options{PuppeteerNodePath: sh, PuppeteerScriptPath: script, HeadlessTimeout: 1}}
+	res := v.Validate("http://example.com", "[headless-check]", "headless")
+	if res.ExecutionDetected || res.Error == nil {
+		t.Fatalf("Validate() = %+v for a failing verifier, want an error", res)
+	}
+	if !strings.Contains(logs.String(), "Cannot find module 'puppeteer'") {
+		t.Errorf("failure log does not include the verifier stderr: %q", logs.String())
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	var logs bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer SetLogger(nil)
+
+	missing := filepath.Join(t.TempDir(), "missing.js")
+	v := puppeteerValidator{options: model.Options{PuppeteerScriptPath: missing}}
+	if res := v.Validate("http://example.com", "[headless-check]", "headless"); res.Error == nil {
+		t.Fatalf("Validate() with a missing script should fail")
+	}
+	if got := logs.String(); !strings.Contains(got, "level=ERROR") || !strings.Contains(got, missing) {
+		t.Errorf("SetLogger() logger got %q, want the verifier error", got)
+	}
+
+	SetLogger(nil)
+	if headlessLogger() != slog.Default() {
+		t.Errorf("SetLogger(nil) did not restore the default")
+	}
+}
+
+func Test_browserManager(t *testing.T) {
+	SetBrowserManager(nil)
+	defer SetBrowserManager(nil)
+	defer func(orig func(model.Options) browser.BrowserConfig) { browserConfig = orig }(browserConfig)
+	snapshots := t.TempDir()
+
+	t.Run("created on first use", func(t *testing.T) {
+		browserConfig = func(options model.Options) browser.BrowserConfig {
+			cfg := browser.ConfigFromOptions(options)
+			cfg.SnapshotDir = snapshots
+			return cfg
+		}
+		m, err := browserManager(model.Options{HeadlessTimeout: 7})
+		if err != nil {
+			t.Fatalf("browserManager() error = %v", err)
+		}
+		defer m.Shutdown()
+		if !m.IsInitialized() {
+			t.Errorf("browserManager() returned an uninitialized manager")
+		}
+		if again, _ := browserManager(model.Options{HeadlessTimeout: 60}); again != m {
+			t.Errorf("browserManager() created a second manager")
+		}
+		SetBrowserManager(nil)
+	})
+
+	t.Run("initialize error", func(t *testing.T) {
+		var logs bytes.Buffer
+		SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+		defer SetLogger(nil)
+		browserConfig = func(options model.Options) browser.BrowserConfig {
+			cfg := browser.ConfigFromOptions(options)
+			cfg.SnapshotDir = snapshots
+			cfg.ChromiumBinaryPath = "/nonexistent/chrome"
+			cfg.VerifyChromeOnInit = true
+			return cfg
+		}
+		if _, err := browserManager(model.Options{}); !errors.Is(err, browser.ErrChromeNotFound) {
+			t.Errorf("browserManager() error = %v, want %v", err, browser.ErrChromeNotFound)
+		}
+		if _, err := browserManager(model.Options{}); !errors.Is(err, browser.ErrChromeNotFound) {
+			t.Errorf("browserManager() on reuse error = %v, want %v", err, browser.ErrChromeNotFound)
+		}
+		if n := strings.Count(logs.String(), "Headless validation is unavailable"); n != 1 {
+			t.Errorf("initialize error logged %d times, want once: %q", n, logs.String())
+		}
+		if res := headlessValidator(model.Options{}).Validate("http://example.com", "[headless-check]", "headless"); !errors.Is(res.Error, browser.ErrChromeNotFound) {
+			t.Errorf("Validate() error = %v, want %v", res.Error, browser.ErrChromeNotFound)
+		}
+	})
+}
+
+func Test_verifierResult_validationResult(t *testing.T) {
+	output := `{
+		"isVulnerable": true,
+		"executionDetected": true,
+		"executionProofs": [{
+			"payloadSHA256": "abc",
+			"executionType": "alert",
+			"executedAt": "2024-05-01T10:00:00.000Z",
+			"evidence": "1",
+			"pageURL": "http://example.com/?q=1",
+			"pageTitle": "Search",
+			"screenshotPath": "/tmp/a.jpg",
+			"screenshotData": "/9hqcGVn",
+			"executionContext": "headless"
+		}],
+		"validationDuration": 1500
+	}`
+	var r verifierResult
+	if err := json.Unmarshal([]byte(output), &r); err != nil {
+		t.Fatal(err)
+	}
+	res := r.validationResult()
+	if !res.ExecutionDetected || res.Error != nil || res.PageTitle != "Search" || res.ValidationDuration != 1500*time.Millisecond {
+		t.Fatalf("validationResult() = %+v", res)
+	}
+	if len(res.ExecutionProofs) != 1 {
+		t.Fatalf("validationResult() has %d proofs, want 1", len(res.ExecutionProofs))
+	}
+
+	var poc model.PoC
+	res.ExecutionProofs[0].ApplyToPoC(&poc)
+	if poc.ExecutionType != "alert" || poc.ScreenshotPath != "/tmp/a.jpg" || poc.ScreenshotBase64 != "/9hqcGVn" || !poc.BrowserValidated {
+		t.Errorf("ApplyToPoC() = %+v, want the Puppeteer evidence", poc)
+	}
+
+	failed := verifierResult{Error: "net::ERR_NAME_NOT_RESOLVED"}.validationResult()
+	if failed.ExecutionDetected || failed.Error == nil || failed.Error.Error() != "net::ERR_NAME_NOT_RESOLVED" {
+		t.Errorf("validationResult() of a failed run = %+v", failed)
+	}
+}
+
+func Test_playwrightValidator(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	// stands in for playwright_verifier.js, echoing the url and engine it was given
+	script := filepath.Join(t.TempDir(), "verifier.sh")
+	verifier := `printf '{"isVulnerable":true,"executionDetected":true,"executionProofs":[{"executionType":"alert","pageURL":"%s","pageTitle":"%s"}]}' "$1" "$6"`
+	if err := os.WriteFile(script, []byte(verifier), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := playwrightValidator{options: model.Options{PuppeteerNodePath: sh, PlaywrightScriptPath: script, HeadlessTimeout: 1, BrowserEngine: "WebKit"}}
+	res := v.Validate("http://example.com/?q=1", "[headless-check]", "headless")
+	if res.Error != nil || !res.ExecutionDetected {
+		t.Fatalf("Validate() = %+v, want execution detected", res)
+	}
+	if got := res.ExecutionProofs[0].PageURL; got != "http://example.com/?q=1" {
+		t.Errorf("PageURL = %q, want the validated url", got)
+	}
+	if got := res.ExecutionProofs[0].PageTitle; got != browser.EngineWebKit {
+		t.Errorf("verifier was passed engine %q, want %q", got, browser.EngineWebKit)
+	}
+	if res.Engine != browser.EngineWebKit {
+		t.Errorf("Engine = %q, want %q", res.Engine, browser.EngineWebKit)
+	}
+}
+
+// urlValidator reports execution for the urls in vulnerable
+type urlValidator struct {
+	vulnerable map[string]bool
+	wait       map[string]chan struct{} // Validate of the url blocks until closed
+}
+
+func (v urlValidator) Validate(url, payload, contextStr string) *browser.ValidationResult {
+	if ch, ok := v.wait[url]; ok {
+		<-ch
+	}
+	return &browser.ValidationResult{ExecutionDetected: v.vulnerable[url]}
+}
+
+func Test_headlessValidator(t *testing.T) {
+	m := browser.NewManager(browser.BrowserConfig{})
+	SetBrowserManager(m)
+	defer SetBrowserManager(nil)
+
+	if v, ok := headlessValidator(model.Options{PuppeteerHeadless: true}).(puppeteerValidator); !ok || !v.options.PuppeteerHeadless {
+		t.Errorf("headlessValidator() with PuppeteerHeadless = %T, want puppeteerValidator", v)
+	}
+	if _, ok := headlessValidator(model.Options{PlaywrightHeadless: true}).(playwrightValidator); !ok {
+		t.Errorf("headlessValidator() with PlaywrightHeadless is not a playwrightValidator")
+	}
+	if _, ok := headlessValidator(model.Options{PuppeteerHeadless: true, BrowserEngine: "firefox"}).(playwrightValidator); !ok {
+		t.Errorf("headlessValidator() with the firefox engine is not a playwrightValidator")
+	}
+	if _, ok := headlessValidator(model.Options{BrowserEngine: " Chromium "}).(chromedpValidator); !ok {
+		t.Errorf("headlessValidator() with the chromium engine is not a chromedpValidator")
+	}
+	if res := headlessValidator(model.Options{BrowserEngine: "firefx"}).Validate("http://example.com", "[headless-check]", "headless"); !errors.Is(res.Error, browser.ErrInvalidEngine) {
+		t.Errorf("Validate() with an unknown engine error = %v, want %v", res.Error, browser.ErrInvalidEngine)
+	}
+	if v, ok := headlessValidator(model.Options{}).(chromedpValidator); !ok || v.manager != m {
+		t.Errorf("headlessValidator() = %T, want chromedpValidator on the shared manager", v)
+	}
+}
+
+func Test_validateURLs(t *testing.T) {
+	urls := []string{"http://a", "http://b", "http://c", "http://d"}
+	v := urlValidator{vulnerable: map[string]bool{"http://b": true, "http://d": true}}
+	results := make([]*browser.ValidationResult, len(urls))
+	calls := 0
+	validateURLs(v, urls, 3, func(i int, result *browser.ValidationResult) {
+		results[i] = result
+		calls++
+	})
+	if calls != len(urls) {
+		t.Fatalf("validateURLs() reported %d results, want %d", calls, len(urls))
+	}
+	for i, url := range urls {
+		if results[i].ExecutionDetected != v.vulnerable[url] {
+			t.Errorf("result %d (%s) ExecutionDetected = %v, want %v", i, url, results[i].ExecutionDetected, v.vulnerable[url])
+		}
+	}
+
+	t.Run("results as each url finishes", func(t *testing.T) {
+		slow := make(chan struct{})
+		v := urlValidator{
+			vulnerable: map[string]bool{"http://b": true},
+			wait:       map[string]chan struct{}{"http://a": slow},
+		}
+		var order []int
+		validateURLs(v, []string{"http://a", "http://b"}, 2, func(i int, result *browser.ValidationResult) {
+			order = append(order, i)
+			if i == 1 {
+				// http://a only finishes after http://b was reported
+				close(slow)
+			}
+		})
+		if !reflect.DeepEqual(order, []int{1, 0}) {
+			t.Errorf("validateURLs() reported urls in order %v, want [1 0]", order)
+		}
+	})
+}
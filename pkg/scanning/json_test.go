@@ -183,7 +183,7 @@ func TestJSONParameterDiscovery(t *testing.T) {
 	rl := &rateLimiter{}
 
 	// Call ParameterAnalysis
-	params := ParameterAnalysis("http://example.com/api/user", options, rl)
+	params, _ := ParameterAnalysis("http://example.com/api/user", options, rl)
 
 	// Check that JSON parameters were discovered
 	jsonParams := 0
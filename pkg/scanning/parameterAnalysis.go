@@ -222,7 +222,28 @@ func findDOMParams(target string, p, dp url.Values, options model.Options) (url.
 	return p, dp
 }
 
-func processParams(target string, paramsQue chan string, results chan model.ParamResult, options model.Options, rl *rateLimiter, miningCheckerLine int, pLog *logrus.Entry) {
+// wafDetection keeps the first WAF seen by the processParams workers, whose
+// options are copies
+type wafDetection struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (w *wafDetection) set(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.name == "" {
+		w.name = name
+	}
+}
+
+func (w *wafDetection) get() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.name
+}
+
+func processParams(target string, paramsQue chan string, results chan model.ParamResult, options model.Options, rl *rateLimiter, miningCheckerLine int, pLog *logrus.Entry, waf *wafDetection) {
 	client := clientPool.Get().(*http.Client)
 	defer clientPool.Put(client)
 	for k := range paramsQue {
@@ -237,6 +258,7 @@ func processParams(target string, paramsQue chan string, results chan model.Para
 				if wafCheck {
 					options.WAF = true
 					options.WAFName = wafN
+					waf.set(wafN)
 					if options.WAFEvasion {
 						options.Concurrence = 1
 						options.Delay = 3
@@ -299,14 +321,16 @@ func processParams(target string, paramsQue chan string, results chan model.Para
 	}
 }
 
-func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) map[string]model.ParamResult {
+// ParameterAnalysis finds the reflected parameters of target and returns them
+// with the name of the WAF detected in their responses, or "" for none
+func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) (map[string]model.ParamResult, string) {
 	miningCheckerLine := 0
 	vLog := vlogger.GetLogger(options.Debug)
 	pLog := vLog.WithField("data1", "PA")
 	_, p, dp, err := parseURL(target)
 	params := make(map[string]model.ParamResult)
 	if err != nil {
-		return params
+		return params, ""
 	}
 
 	for tempP := range p {
@@ -395,6 +419,7 @@ func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) ma
 	results := make(chan model.ParamResult, concurrency)
 	miningDictCount := 0
 	mutex := &sync.Mutex{}
+	waf := &wafDetection{}
 
 	go func() {
 		for result := range results {
@@ -407,7 +432,7 @@ func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) ma
 	for i := 0; i < concurrency; i++ {
 		wgg.Add(1)
 		go func() {
-			processParams(target, paramsQue, results, options, rl, miningCheckerLine, pLog)
+			processParams(target, paramsQue, results, options, rl, miningCheckerLine, pLog, waf)
 			wgg.Done()
 		}()
 	}
@@ -439,7 +464,7 @@ func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) ma
 	for j := 0; j < concurrency; j++ {
 		wggg.Add(1)
 		go func() {
-			processParams(target, paramsDataQue, results, options, rl, miningCheckerLine, pLog)
+			processParams(target, paramsDataQue, results, options, rl, miningCheckerLine, pLog, waf)
 			wggg.Done()
 		}()
 	}
@@ -461,10 +486,11 @@ func ParameterAnalysis(target string, options model.Options, rl *rateLimiter) ma
 	if miningDictCount != 0 {
 		printing.DalLog("INFO", "Found "+strconv.Itoa(miningDictCount)+" testing points in dictionary-based parameter mining", options)
 	}
-	if options.WAF {
-		printing.DalLog("INFO", "Detected WAF: "+options.WAFName, options)
+	wafName := waf.get()
+	if wafName != "" {
+		printing.DalLog("INFO", "Detected WAF: "+wafName, options)
 	}
-	return params
+	return params, wafName
 }
 
 // GetPType is Get Parameter Type
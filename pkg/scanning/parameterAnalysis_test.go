@@ -309,7 +309,7 @@ func TestParameterAnalysis(t *testing.T) {
 	rl := newRateLimiter(time.Duration(0))
 
 	target := ts.URL + "?test=aaa" // Changed from "value" to "Dalfox" to match server logic
-	results := ParameterAnalysis(target, options, rl)
+	results, _ := ParameterAnalysis(target, options, rl)
 
 	// Verify that the "test" parameter was found and marked as reflected
 	if param, exists := results["test"]; !exists || !param.Reflected {
@@ -216,7 +216,13 @@ func Scan(target string, options model.Options, sid string) (model.Result, error
 	var pathReflection map[int]string
 	var params map[string]model.ParamResult
 	if !options.SkipDiscovery {
-		policy, pathReflection, params = performDiscovery(target, options, rl)
+		var wafName string
+		policy, pathReflection, params, wafName = performDiscovery(target, options, rl)
+		if wafName != "" {
+			// payload generation tailors the custom payloads to the WAF
+			options.WAF = true
+			options.WAFName = wafName
+		}
 	} else {
 		printing.DalLog("SYSTEM", "Skipping discovery phase as requested with --skip-discovery", options)
 		policy = make(map[string]string)
@@ -297,6 +303,14 @@ func getBlindCallbackURL(blindURL string) string {
 	return "//" + blindURL
 }
 
+// payloadVars returns the values for {KEY} tokens in custom payloads
+func payloadVars(options model.Options) map[string]string {
+	if options.BlindURL == "" {
+		return nil
+	}
+	return map[string]string{"CALLBACK": getBlindCallbackURL(options.BlindURL)}
+}
+
 func generatePayloads(target string, options model.Options, policy map[string]string, pathReflection map[int]string, params map[string]model.ParamResult) (map[*http.Request]map[string]string, []string) {
 	query := make(map[*http.Request]map[string]string)
 	var durls []string
@@ -340,10 +354,23 @@ func generatePayloads(target string, options model.Options, policy map[string]st
 
 	// Custom Payload (merged with defaults and context-aware)
 	if (options.SkipDiscovery || utils.IsAllowType(policy["Content-Type"])) && options.CustomPayloadFile != "" {
-		merged, err := payload.LoadMergedPayloads(options.CustomPayloadFile)
+		merged, err := payload.LoadMergedPayloadsMetaWithVars(options.CustomPayloadFile, payloadVars(options))
 		if err != nil {
 			printing.DalLog("SYSTEM", "Failed to load custom XSS payload file: "+err.Error(), options)
 		} else {
+			if options.WAF {
+				// try the encodings a filter may let through, those known to
+				// bypass the detected WAF first
+				merged = payload.WithEncodingVariants(merged)
+				merged = payload.SelectForWAFMeta(merged, options.WAFName)
+			}
+			if options.MaxPayloadsPerContext > 0 {
+				var dropped int
+				merged, dropped = payload.LimitPayloads(merged, options.MaxPayloadsPerContext)
+				if dropped > 0 {
+					printing.DalLog("SYSTEM", "Capped custom XSS payloads at "+strconv.Itoa(options.MaxPayloadsPerContext)+" per context, skipping "+strconv.Itoa(dropped), options)
+				}
+			}
 			// Count total custom entries for logging
 			total := 0
 			for _, lst := range merged {
@@ -356,12 +383,13 @@ func generatePayloads(target string, options model.Options, policy map[string]st
 				// Determine context for this parameter
 				ctxType := "html"
 				if options.ContextAware {
-					ctxType = utils.DetectContext(v.ReflectedCode, k, "test")
+					// ReflectedCode is the snippet around the "Dalfox" probe from parameter analysis
+					ctxType = payload.DetectContext(v.ReflectedCode, "Dalfox")
 					printing.DalLog("INFO", "Detected context for "+k+": "+ctxType, options)
 				}
 
 				// choose payload list based on context
-				var payloadList []string
+				var payloadList []payload.PayloadMeta
 				switch strings.ToLower(ctxType) {
 				case "attribute", "attr":
 					payloadList = append(payloadList, merged["ATTR"]...)
@@ -373,10 +401,12 @@ func generatePayloads(target string, options model.Options, policy map[string]st
 					// html and default
 					payloadList = append(payloadList, merged["HTML"]...)
 					payloadList = append(payloadList, merged["ANY"]...)
+					// mutation payloads are inert until the parser rewrites markup
+					payloadList = append(payloadList, merged["MXSS"]...)
 				}
 
 				for _, customPayload := range payloadList {
-					if customPayload == "" {
+					if customPayload.Payload == "" {
 						continue
 					}
 					ptype := ""
@@ -386,8 +416,15 @@ func generatePayloads(target string, options model.Options, policy map[string]st
 						}
 					}
 					encoders := []string{NaN, urlEncode, urlDoubleEncode, htmlEncode}
+					if customPayload.Encoding != "" {
+						// already an encoded variant; encoding it again breaks the bypass
+						encoders = []string{NaN}
+					}
 					for _, encoder := range encoders {
-						tq, tm := optimization.MakeRequestQuery(target, k, customPayload, "inHTML"+ptype, "toAppend", encoder, options)
+						tq, tm := optimization.MakeRequestQuery(target, k, customPayload.Payload, "inHTML"+ptype, "toAppend", encoder, options)
+						tm["cwe"] = customPayload.CWE
+						tm["severity"] = customPayload.Severity
+						tm["sink"] = customPayload.Sink
 						query[tq] = tm
 					}
 				}
@@ -2,15 +2,18 @@ package scanning
 
 import (
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/hahwul/dalfox/v2/internal/payload"
 	"github.com/hahwul/dalfox/v2/pkg/model"
 	"github.com/logrusorgru/aurora"
 	"github.com/stretchr/testify/assert"
@@ -461,6 +464,98 @@ func Test_generatePayloads(t *testing.T) {
 	}
 }
 
+func Test_generatePayloads_encodingVariants(t *testing.T) {
+	const custom = "<svg/onload=alert(1)>"
+	path := filepath.Join(t.TempDir(), "custom.txt")
+	if err := os.WriteFile(path, []byte(custom+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := model.Options{
+		SkipDiscovery:     true,
+		CustomPayloadFile: path,
+		WAF:               true,
+		Silence:           true,
+		NoSpinner:         true,
+		CustomAlertType:   "none",
+	}
+	params := map[string]model.ParamResult{
+		"q": {Name: "q", Type: "URL", Reflected: true},
+	}
+	variants := make(map[string]bool)
+	for _, v := range payload.EncodingVariants(custom) {
+		variants[v.Payload] = true
+	}
+
+	query, _ := generatePayloads("http://example.com/?q=1", options, map[string]string{}, map[int]string{}, params)
+
+	encodes := make(map[string][]string)
+	for _, tm := range query {
+		if tm["payload"] == custom || variants[tm["payload"]] {
+			encodes[tm["payload"]] = append(encodes[tm["payload"]], tm["encode"])
+		}
+	}
+	if got := len(encodes[custom]); got != 4 {
+		t.Errorf("original payload sent with %d encoders, want 4", got)
+	}
+	for v := range variants {
+		if got := encodes[v]; len(got) != 1 || got[0] != NaN {
+			t.Errorf("variant %q sent with encoders %v, want only %q", v, got, NaN)
+		}
+	}
+}
+
+func Test_Scan_wafTailoredPayloads(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		mu.Lock()
+		received[q] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "cloudflare")
+		fmt.Fprintf(w, "<html><body><p>%s</p></body></html>", html.EscapeString(q))
+	}))
+	defer server.Close()
+
+	const preferred, blocked = "<svg/onload=alert(1)>", "<script>alert(1)</script>"
+	path := filepath.Join(t.TempDir(), "custom.txt")
+	if err := os.WriteFile(path, []byte(preferred+"\n"+blocked+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := model.Options{
+		Concurrence:       10,
+		Format:            "plain",
+		Silence:           true,
+		NoSpinner:         true,
+		CustomAlertType:   "none",
+		CustomPayloadFile: path,
+		OnlyCustomPayload: true,
+		Scan:              make(map[string]model.Scan),
+		AuroraObject:      aurora.NewAurora(false),
+	}
+	if _, err := Scan(server.URL+"/?q=1", options, "1"); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var variant string
+	for _, v := range payload.EncodingVariants(preferred) {
+		if v.Encoding == payload.EncodingURL {
+			variant = v.Payload
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !received["1"+variant] {
+		t.Errorf("Scan() did not send the %s variant %q of a custom payload behind the detected WAF", payload.EncodingURL, variant)
+	}
+	for q := range received {
+		if strings.Contains(q, blocked) {
+			t.Errorf("Scan() sent %q, which the detected WAF is known to block", q)
+		}
+	}
+}
+
 func Test_createHTTPClient(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -7,8 +7,10 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/hahwul/dalfox/v2/internal/browser"
 	"github.com/hahwul/dalfox/v2/internal/har"
 	"github.com/hahwul/dalfox/v2/internal/optimization"
+	"github.com/hahwul/dalfox/v2/internal/payload"
 	"github.com/hahwul/dalfox/v2/internal/printing"
 	"github.com/hahwul/dalfox/v2/internal/utils"
 	"github.com/hahwul/dalfox/v2/internal/verification"
@@ -49,68 +51,51 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 	if options.UseHeadless {
 		wg.Add(1)
 		go func() {
-			dconcurrency := options.Concurrence / 2
-			if dconcurrency < 1 {
-				dconcurrency = 1
-			}
-			if dconcurrency > 10 {
-				dconcurrency = 10
-			}
-			dchan := make(chan string)
-			var wgg sync.WaitGroup
-			for i := 0; i < dconcurrency; i++ {
-				wgg.Add(1)
-				go func() {
-					for v := range dchan {
-						// Use Puppeteer if flag is enabled, regardless of phase
-						if CheckXSSWithHeadless(v, options) {
-							printing.DalLog("VULN", "Triggered XSS Payload (found dialog in headless)", options)
-							poc := model.PoC{
-								Type:       "V",
-								InjectType: "headless",
-								Method:     "GET",
-								Data:       v,
-								Param:      "",
-								Payload:    "",
-								Evidence:   "",
-								CWE:        "CWE-79",
-								Severity:   "High",
-								PoCType:    options.PoCType,
-								MessageStr: "Triggered XSS Payload (found dialog in headless)",
-							}
-							if options.Beef {
-								poc.BeEFHookActive = true
-								poc.BeEFHookID = "beef_hook_" + target
-								poc.BeEFHookCount = 1
-							}
-							if showV {
-								switch options.Format {
-								case "json":
-									pocj, _ := json.Marshal(poc)
-									printing.DalLog("PRINT", string(pocj)+",", options)
-								case "jsonl":
-									pocj, _ := json.Marshal(poc)
-									printing.DalLog("PRINT", string(pocj), options)
-								default:
-									pocsStr := "[" + poc.Type + "][" + poc.Method + "][" + poc.InjectType + "] " + poc.Data
-									printing.DalLog("PRINT", pocsStr, options)
-								}
-							}
-							if options.FoundAction != "" {
-								foundAction(options, target, v, "VULN")
-							}
-							resultsChan <- poc
+			CheckURLsWithHeadless(durls, options, func(i int, headless *browser.ValidationResult, found bool) {
+				if found {
+					v := durls[i]
+					printing.DalLog("VULN", "Triggered XSS Payload (found dialog in headless)", options)
+					poc := model.PoC{
+						Type:       "V",
+						InjectType: "headless",
+						Method:     "GET",
+						Data:       v,
+						Param:      "",
+						Payload:    "",
+						Evidence:   "",
+						CWE:        "CWE-79",
+						Severity:   "High",
+						PoCType:    options.PoCType,
+						MessageStr: "Triggered XSS Payload (found dialog in headless)",
+					}
+					if len(headless.ExecutionProofs) > 0 {
+						headless.ExecutionProofs[0].ApplyToPoC(&poc)
+					}
+					if options.Beef {
+						poc.BeEFHookActive = true
+						poc.BeEFHookID = "beef_hook_" + target
+						poc.BeEFHookCount = 1
+					}
+					if showV {
+						switch options.Format {
+						case "json":
+							pocj, _ := json.Marshal(poc)
+							printing.DalLog("PRINT", string(pocj)+",", options)
+						case "jsonl":
+							pocj, _ := json.Marshal(poc)
+							printing.DalLog("PRINT", string(pocj), options)
+						default:
+							pocsStr := "[" + poc.Type + "][" + poc.Method + "][" + poc.InjectType + "] " + poc.Data
+							printing.DalLog("PRINT", pocsStr, options)
 						}
-						queryCount++
 					}
-					wgg.Done()
-				}()
-			}
-			for _, dchanData := range durls {
-				dchan <- dchanData
-			}
-			close(dchan)
-			wgg.Wait()
+					if options.FoundAction != "" {
+						foundAction(options, target, v, "VULN")
+					}
+					resultsChan <- poc
+				}
+				queryCount++
+			})
 			wg.Done()
 		}()
 	}
@@ -138,7 +123,12 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 						if strings.Contains(v["type"], "inJS") && vrs {
 							protected := verification.VerifyReflection(resbody, "\\"+v["payload"]) && !strings.Contains(v["payload"], "\\")
 							if !protected && !vStatus[v["param"]] {
-								if options.UseHeadless && CheckXSSWithHeadless(k.URL.String(), options) {
+								var headless *browser.ValidationResult
+								confirmed := false
+								if options.UseHeadless {
+									headless, confirmed = CheckXSSWithHeadless(k.URL.String(), options)
+								}
+								if confirmed {
 									poc := model.PoC{
 										Type:       "V",
 										InjectType: v["type"],
@@ -153,11 +143,15 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 										MessageID:  har.MessageIDFromRequest(k),
 										MessageStr: "Triggered XSS Payload (found dialog in headless)",
 									}
+									if len(headless.ExecutionProofs) > 0 {
+										headless.ExecutionProofs[0].ApplyToPoC(&poc)
+									}
 									if options.Beef {
 										poc.BeEFHookActive = true
 										poc.BeEFHookID = "beef_hook_" + target
 										poc.BeEFHookCount = 1
 									}
+									stampPayloadMeta(&poc, v)
 									printing.LogPoC(&poc, resbody, k, options, showV, "VULN", "Triggered XSS Payload (found dialog in headless)")
 									vStatus[v["param"]] = true
 									if options.FoundAction != "" {
@@ -179,6 +173,7 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 										MessageID:  har.MessageIDFromRequest(k),
 										MessageStr: "Reflected Payload in JS: " + v["param"] + "=" + v["payload"],
 									}
+									stampPayloadMeta(&poc, v)
 									printing.LogPoC(&poc, resbody, k, options, showR, "WEAK", "Reflected Payload in JS: "+v["param"]+"="+v["payload"])
 									if options.FoundAction != "" {
 										foundAction(options, target, k.URL.String(), "WEAK")
@@ -207,6 +202,7 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 									poc.BeEFHookID = "beef_hook_" + target
 									poc.BeEFHookCount = 1
 								}
+								stampPayloadMeta(&poc, v)
 								printing.LogPoC(&poc, resbody, k, options, showV, "VULN", "Triggered XSS Payload (found DOM Object): "+v["param"]+"="+v["payload"])
 								vStatus[v["param"]] = true
 								if options.FoundAction != "" {
@@ -228,6 +224,7 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 									MessageID:  har.MessageIDFromRequest(k),
 									MessageStr: "Reflected Payload in Attribute: " + v["param"] + "=" + v["payload"],
 								}
+								stampPayloadMeta(&poc, v)
 								printing.LogPoC(&poc, resbody, k, options, showR, "WEAK", "Reflected Payload in Attribute: "+v["param"]+"="+v["payload"])
 								if options.FoundAction != "" {
 									foundAction(options, target, k.URL.String(), "WEAK")
@@ -255,6 +252,7 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 									poc.BeEFHookID = "beef_hook_" + target
 									poc.BeEFHookCount = 1
 								}
+								stampPayloadMeta(&poc, v)
 								printing.LogPoC(&poc, resbody, k, options, showV, "VULN", "Triggered XSS Payload (found DOM Object): "+v["param"]+"="+v["payload"])
 								vStatus[v["param"]] = true
 								if options.FoundAction != "" {
@@ -276,6 +274,7 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 									MessageID:  har.MessageIDFromRequest(k),
 									MessageStr: "Reflected Payload in HTML: " + v["param"] + "=" + v["payload"],
 								}
+								stampPayloadMeta(&poc, v)
 								printing.LogPoC(&poc, resbody, k, options, showR, "WEAK", "Reflected Payload in HTML: "+v["param"]+"="+v["payload"])
 								if options.FoundAction != "" {
 									foundAction(options, target, k.URL.String(), "WEAK")
@@ -305,3 +304,19 @@ func performScanning(target string, options model.Options, query map[*http.Reque
 	<-doneChan
 	return pocs
 }
+
+// stampPayloadMeta overrides the CWE and severity of poc with the values the
+// payload definition of query v carries, if any, and records the sink class of
+// the payload.
+func stampPayloadMeta(poc *model.PoC, v map[string]string) {
+	poc.SinkClass = v["sink"]
+	if poc.SinkClass == "" {
+		poc.SinkClass = payload.ClassifyPayload(v["payload"])
+	}
+	if cwe := v["cwe"]; cwe != "" {
+		poc.CWE = cwe
+	}
+	if severity := v["severity"]; severity != "" {
+		poc.Severity = severity
+	}
+}
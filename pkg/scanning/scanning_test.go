@@ -1,11 +1,16 @@
 package scanning
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/hahwul/dalfox/v2/internal/optimization"
 	"github.com/hahwul/dalfox/v2/pkg/model"
 )
 
@@ -91,3 +96,43 @@ func createTestRateLimiter() *rateLimiter {
 	rl := newRateLimiter(time.Duration(0))
 	return rl
 }
+
+func Test_performScanning_headlessInJS(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	// a verifier confirming every payload, with the dialog message as evidence
+	script := filepath.Join(t.TempDir(), "verifier.sh")
+	output := `{"isVulnerable":true,"executionDetected":true,"executionProofs":[{"executionType":"alert","evidence":"dalfox-evidence"}]}`
+	if err := os.WriteFile(script, []byte("echo '"+output+"'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><script>var a = \"%s\";</script></html>", r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+
+	options := model.Options{
+		Concurrence:         1,
+		Format:              "plain",
+		Silence:             true,
+		NoSpinner:           true,
+		UseHeadless:         true,
+		PuppeteerHeadless:   true,
+		PuppeteerNodePath:   sh,
+		PuppeteerScriptPath: script,
+	}
+	const xss = `";alert(1);//`
+	req, tm := optimization.MakeRequestQuery(server.URL+"/?q=", "q", xss, "inJS-double", "toAppend", "NaN", options)
+	query := map[*http.Request]map[string]string{req: tm}
+
+	pocs := performScanning(server.URL, options, query, nil, createTestRateLimiter(), map[string]bool{"q": false})
+	if len(pocs) != 1 || pocs[0].Type != "V" {
+		t.Fatalf("performScanning() = %+v, want one headless-confirmed PoC", pocs)
+	}
+	if pocs[0].Evidence != "dalfox-evidence" || !pocs[0].BrowserValidated {
+		t.Errorf("PoC Evidence = %q, BrowserValidated = %v, want the headless proof applied", pocs[0].Evidence, pocs[0].BrowserValidated)
+	}
+}
@@ -135,7 +135,7 @@ func processResponse(str string, resp *http.Response, payload string, req *http.
 		vrs := verification.VerifyReflection(str, payload)
 		if !vds && options.ForceHeadlessVerification {
 			// Only run headless verification if VerifyDOM failed
-			vds = CheckXSSWithHeadless(req.URL.String(), options)
+			_, vds = CheckXSSWithHeadless(req.URL.String(), options)
 		}
 		rLog.WithField("data2", "vds").Debug(vds)
 		rLog.WithField("data2", "vrs").Debug(vrs)